@@ -0,0 +1,168 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"reflect"
+)
+
+/*
+Schema is a code-first schema of rows of type T, use it for types that
+can't be tagged, e.g. generated protobuf messages.
+
+	schema := excelizemapper.NewSchema[User]().
+		Column("Name", func(u User) any { return u.Name }, excelizemapper.Width(20)).
+		Column("Sex", func(u User) any { return u.Sex }, excelizemapper.Formatter("sex"),
+			excelizemapper.Setter(func(u *User, sex Sex) { u.Sex = sex }))
+
+	m := excelizemapper.NewExcelizeMapper(excelizemapper.WithSchema(schema))
+*/
+type Schema[T any] struct {
+	columns []Column
+	err     error
+}
+
+// ColumnOption configures column of Schema
+type ColumnOption func(*Column)
+
+// NewSchema creates empty schema of T, T is a struct or a pointer to struct.
+func NewSchema[T any]() *Schema[T] {
+	return &Schema[T]{}
+}
+
+// Column adds column with value returned by get, columns are placed in the
+// order they are added unless Index is set.
+func (s *Schema[T]) Column(header string, get func(T) any, opts ...ColumnOption) *Schema[T] {
+	col := Column{
+		ColumnIndex: len(s.columns),
+		HeaderName:  header,
+		FieldName:   header,
+		getter: func(row reflect.Value) reflect.Value {
+			return reflect.ValueOf(get(rowAs[T](row)))
+		},
+	}
+	for _, opt := range opts {
+		opt(&col)
+	}
+
+	if rowPtr := reflect.PointerTo(rowTypeOfSchema[T]()); col.setter != nil && col.setterRow != rowPtr {
+		s.err = fmt.Errorf("column %s: setter of %s used in schema of %s", header, col.setterRow, rowPtr)
+	}
+
+	s.columns = append(s.columns, col)
+	return s
+}
+
+// Columns returns copy of schema columns
+func (s *Schema[T]) Columns() []Column {
+	columns, _ := copySchema(s.columns, nil)
+	return columns
+}
+
+func (s *Schema[T]) schemaType() reflect.Type {
+	return rowTypeOfSchema[T]()
+}
+
+func (s *Schema[T]) schemaColumns() ([]Column, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	columns, _ := copySchema(s.columns, nil)
+	sortColumns(columns)
+	return columns, nil
+}
+
+// schemaProvider is implemented by Schema of any type
+type schemaProvider interface {
+	schemaType() reflect.Type
+	schemaColumns() ([]Column, error)
+}
+
+// WithSchema set code-first schema used instead of tags for rows of its type
+func WithSchema(schema schemaProvider) Option {
+	return func(o *options) {
+		o.schemas[schema.schemaType()] = schema
+	}
+}
+
+// Width set column width
+func Width(width float64) ColumnOption {
+	return func(c *Column) {
+		c.ColumnWidth = width
+	}
+}
+
+// Index set column index
+func Index(index int) ColumnOption {
+	return func(c *Column) {
+		c.ColumnIndex = index
+	}
+}
+
+// OmitEmpty writes zero value as empty cell
+func OmitEmpty() ColumnOption {
+	return func(c *Column) {
+		c.OmitEmpty = true
+	}
+}
+
+// Default set value used for zero values
+func Default(value string) ColumnOption {
+	return func(c *Column) {
+		c.DefaultValue = value
+	}
+}
+
+// Formatter set formatter name
+func Formatter(name string) ColumnOption {
+	return func(c *Column) {
+		c.FormatterKey = name
+	}
+}
+
+// NumFmt set number format of cells
+func NumFmt(format string) ColumnOption {
+	return func(c *Column) {
+		c.NumFmt = format
+	}
+}
+
+// Style set style name registered by WithStyle
+func Style(name string) ColumnOption {
+	return func(c *Column) {
+		c.StyleKey = name
+	}
+}
+
+// Setter set function used by GetData to fill column value, P is a pointer
+// to row type. Cell text is converted to V the same way as for tagged fields.
+func Setter[P, V any](set func(P, V)) ColumnOption {
+	return func(c *Column) {
+		c.setterRow = reflect.TypeOf((*P)(nil)).Elem()
+		c.setType = reflect.TypeOf((*V)(nil)).Elem()
+		c.setter = func(row reflect.Value, val reflect.Value) {
+			v, _ := val.Interface().(V)
+			set(row.Addr().Interface().(P), v)
+		}
+	}
+}
+
+// rowAs returns row as T, T may be a pointer to row type
+func rowAs[T any](row reflect.Value) T {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Ptr {
+		return row.Interface().(T)
+	}
+	if !row.CanAddr() {
+		copied := reflect.New(row.Type())
+		copied.Elem().Set(row)
+		row = copied.Elem()
+	}
+	return row.Addr().Interface().(T)
+}
+
+func rowTypeOfSchema[T any]() reflect.Type {
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
+	}
+	return rowType
+}
@@ -0,0 +1,386 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatterFactory builds formatter and its parser from tag argument,
+// e.g. factory "time" is called with "02.01.2006" for "format:time(02.01.2006)"
+// and with "" for "format:time()". Bare "format:time" does not use factory.
+type FormatterFactory func(arg string) (Format, Parse, error)
+
+// WithFormatterFactory set formatter factory, formatter registered by
+// WithFormatter with the same full name takes precedence.
+func WithFormatterFactory(name string, factory FormatterFactory) Option {
+	return func(o *options) {
+		o.factories[name] = factory
+	}
+}
+
+/*
+builtinFactories are registered by default:
+
+	time(2006-01-02)        time.Time by layout, default layout is time.DateTime
+	bool(Yes,No)            labels of true and false
+	enum(0=Male,1=Female)   labels of values
+
+Factories are used only for names with argument list, so bare "format:time"
+keeps meaning formatter registered by WithFormatter, if any.
+*/
+func builtinFactories() map[string]FormatterFactory {
+	return map[string]FormatterFactory{
+		"time": timeFactory,
+		"bool": boolFactory,
+		"enum": enumFactory,
+	}
+}
+
+/*
+numberFactories write numbers with native cell type and number format,
+they are used unless factory of the same name is registered:
+
+	fixed(2)                number rounded to precision, default is 2, numfmt "0.00"
+	thousands(,)            number with numfmt "#,##0", "#,##0.##########" if it has fraction
+	percent(1)              fraction rounded to percent precision, default is 0, numfmt "0.0%"
+
+Excel shows thousands separator of its locale, so thousands accepts only ","
+as separator argument.
+*/
+var numberFactories = map[string]func(arg string) (*factoryFormatter, error){
+	"fixed":     fixedFactory,
+	"thousands": thousandsFactory,
+	"percent":   percentFactory,
+}
+
+type factoryFormatter struct {
+	format Format
+	parse  Parse
+	// value returns typed cell value written with numFmt instead of format,
+	// fractionNumFmt is used instead of numFmt for values with fraction if set
+	value          func(interface{}) interface{}
+	numFmt         string
+	fractionNumFmt string
+}
+
+// formatter returns formatter registered with name or built by factory
+func (em *ExcelizeMapper) lookupFormatter(name string) (Format, bool, error) {
+	if format, ok := em.options.formatterMap[name]; ok {
+		return format, true, nil
+	}
+	ff, err := em.factoryFormatter(name)
+	if ff == nil || err != nil {
+		return nil, false, err
+	}
+	return ff.format, true, nil
+}
+
+// parser returns parser registered with name or built by factory
+func (em *ExcelizeMapper) lookupParser(name string) (Parse, bool, error) {
+	if parse, ok := em.options.parserMap[name]; ok {
+		return parse, true, nil
+	}
+	ff, err := em.factoryFormatter(name)
+	if ff == nil || ff.parse == nil || err != nil {
+		return nil, false, err
+	}
+	return ff.parse, true, nil
+}
+
+func (em *ExcelizeMapper) factoryFormatter(name string) (*factoryFormatter, error) {
+	factoryName, arg, hasArg := strings.Cut(name, "(")
+	if !hasArg {
+		return nil, nil
+	}
+	// unknown factory is cached as nil, so cells don't parse its name again
+	if cached, ok := em.options.factoryCache.Load(name); ok {
+		return cached.(*factoryFormatter), nil
+	}
+
+	if !strings.HasSuffix(arg, ")") {
+		return nil, fmt.Errorf("formatter %q: missing ')'", name)
+	}
+	arg = strings.TrimSuffix(arg, ")")
+	factoryName = strings.TrimSpace(factoryName)
+
+	var ff *factoryFormatter
+	if factory, ok := em.options.factories[factoryName]; ok {
+		format, parse, err := factory(arg)
+		if err != nil {
+			return nil, fmt.Errorf("formatter %q: %w", name, err)
+		}
+		ff = &factoryFormatter{format: format, parse: parse}
+	} else if factory, ok := numberFactories[factoryName]; ok {
+		var err error
+		if ff, err = factory(arg); err != nil {
+			return nil, fmt.Errorf("formatter %q: %w", name, err)
+		}
+	}
+
+	em.options.factoryCache.Store(name, ff)
+	return ff, nil
+}
+
+// numberFormatter returns built-in number formatter of column, formatter and
+// context formatter registered with the same name take precedence.
+func (em *ExcelizeMapper) numberFormatter(name string) (*factoryFormatter, error) {
+	if _, ok := em.options.formatterMap[name]; ok {
+		return nil, nil
+	}
+	if _, ok := em.options.contextMap[name]; ok {
+		return nil, nil
+	}
+	ff, err := em.factoryFormatter(name)
+	if ff == nil || ff.value == nil || err != nil {
+		return nil, err
+	}
+	return ff, nil
+}
+
+func newFactoryCache() *sync.Map {
+	return &sync.Map{}
+}
+
+func timeFactory(layout string) (Format, Parse, error) {
+	if layout == "" {
+		layout = time.DateTime
+	}
+	format := func(val interface{}) string {
+		t, ok := val.(time.Time)
+		if !ok || t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	}
+	parse := func(text string) (interface{}, error) {
+		if text == "" {
+			return nil, nil
+		}
+		return time.Parse(layout, text)
+	}
+	return format, parse, nil
+}
+
+func boolFactory(arg string) (Format, Parse, error) {
+	labels := strings.Split(arg, ",")
+	if len(labels) != 2 {
+		return nil, nil, fmt.Errorf("want labels of true and false, got %q", arg)
+	}
+	yes, no := strings.TrimSpace(labels[0]), strings.TrimSpace(labels[1])
+
+	format := func(val interface{}) string {
+		b, ok := val.(bool)
+		switch {
+		case !ok:
+			return ""
+		case b:
+			return yes
+		default:
+			return no
+		}
+	}
+	parse := func(text string) (interface{}, error) {
+		switch {
+		case text == "":
+			return nil, nil
+		case strings.EqualFold(text, yes):
+			return true, nil
+		case strings.EqualFold(text, no):
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid bool %q, want %q or %q", text, yes, no)
+	}
+	return format, parse, nil
+}
+
+func enumFactory(arg string) (Format, Parse, error) {
+	labels := make(map[string]string)
+	values := make(map[string]string)
+	for _, pair := range strings.Split(arg, ",") {
+		value, label, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid enum pair %q, want value=label", pair)
+		}
+		value, label = strings.TrimSpace(value), strings.TrimSpace(label)
+		labels[value] = label
+		values[label] = value
+	}
+
+	format := func(val interface{}) string {
+		text := fmt.Sprint(val)
+		if label, ok := labels[text]; ok {
+			return label
+		}
+		return text
+	}
+	parse := func(text string) (interface{}, error) {
+		if text == "" {
+			return nil, nil
+		}
+		if value, ok := values[text]; ok {
+			return value, nil
+		}
+		if _, ok := labels[text]; ok {
+			return text, nil
+		}
+		return nil, fmt.Errorf("unknown enum label %q", text)
+	}
+	return format, parse, nil
+}
+
+func fixedFactory(arg string) (*factoryFormatter, error) {
+	prec, err := precision(arg, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &factoryFormatter{
+		format: func(val interface{}) string {
+			f, ok := numberOf(val)
+			if !ok {
+				return ""
+			}
+			return strconv.FormatFloat(f, 'f', prec, 64)
+		},
+		parse:  parseNumber,
+		value:  roundNumber(prec),
+		numFmt: decimalNumFmt("0", prec),
+	}, nil
+}
+
+func thousandsFactory(sep string) (*factoryFormatter, error) {
+	if sep == "" {
+		sep = ","
+	}
+	if sep != "," {
+		return nil, fmt.Errorf("separator %q is not supported, Excel shows separator of its locale", sep)
+	}
+	return &factoryFormatter{
+		format: func(val interface{}) string {
+			f, ok := numberOf(val)
+			if !ok {
+				return ""
+			}
+			return groupThousands(strconv.FormatFloat(f, 'f', -1, 64), sep)
+		},
+		parse: func(text string) (interface{}, error) {
+			return parseNumber(strings.ReplaceAll(text, sep, ""))
+		},
+		value: func(val interface{}) interface{} {
+			if f, ok := numberOf(val); ok {
+				return f
+			}
+			return ""
+		},
+		numFmt:         "#,##0",
+		fractionNumFmt: "#,##0.##########",
+	}, nil
+}
+
+func percentFactory(arg string) (*factoryFormatter, error) {
+	prec, err := precision(arg, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &factoryFormatter{
+		format: func(val interface{}) string {
+			f, ok := numberOf(val)
+			if !ok {
+				return ""
+			}
+			return strconv.FormatFloat(f*100, 'f', prec, 64) + "%"
+		},
+		parse: func(text string) (interface{}, error) {
+			text = strings.TrimSpace(text)
+			// number cell is read as raw fraction
+			if !strings.HasSuffix(text, "%") {
+				return parseNumber(text)
+			}
+			f, err := parseNumber(strings.TrimSuffix(text, "%"))
+			if f == nil || err != nil {
+				return nil, err
+			}
+			return f.(float64) / 100, nil
+		},
+		value:  roundNumber(prec + 2),
+		numFmt: decimalNumFmt("0", prec) + "%",
+	}, nil
+}
+
+// roundNumber returns value formatter rounding numbers to prec decimals
+func roundNumber(prec int) func(interface{}) interface{} {
+	scale := math.Pow(10, float64(prec))
+	return func(val interface{}) interface{} {
+		f, ok := numberOf(val)
+		if !ok {
+			return ""
+		}
+		return math.Round(f*scale) / scale
+	}
+}
+
+// decimalNumFmt appends prec decimal places to integer number format
+func decimalNumFmt(integer string, prec int) string {
+	if prec == 0 {
+		return integer
+	}
+	return integer + "." + strings.Repeat("0", prec)
+}
+
+func precision(arg string, def int) (int, error) {
+	if arg == "" {
+		return def, nil
+	}
+	prec, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || prec < 0 {
+		return 0, fmt.Errorf("invalid precision %q", arg)
+	}
+	return prec, nil
+}
+
+func numberOf(val interface{}) (float64, bool) {
+	v := reflect.ValueOf(val)
+	if !v.IsValid() || !isNumber(v) {
+		return 0, false
+	}
+	return toFloat(v), true
+}
+
+func parseNumber(text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", text)
+	}
+	return f, nil
+}
+
+// groupThousands inserts sep into integer part of formatted number
+func groupThousands(number, sep string) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(number, ".")
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteRune(c)
+	}
+	if hasFraction {
+		sb.WriteString(".")
+		sb.WriteString(fraction)
+	}
+	return sb.String()
+}
@@ -0,0 +1,35 @@
+package excelizemapper
+
+import (
+	"reflect"
+	"sync"
+)
+
+// schemaCache holds compiled schemas by row type and parser settings,
+// schemas are shared between mappers and must not be modified.
+var schemaCache sync.Map
+
+type schemaCacheKey struct {
+	itemType reflect.Type
+	parser   parser
+}
+
+type cachedSchema struct {
+	columns []Column
+	rules   []*DynamicRules
+	err     error
+}
+
+// parseType parses schema of struct type, result is cached
+func (p *parser) parseType(itemType reflect.Type) ([]Column, []*DynamicRules, error) {
+	key := schemaCacheKey{itemType: itemType, parser: *p}
+	if cached, ok := schemaCache.Load(key); ok {
+		schema := cached.(*cachedSchema)
+		return schema.columns, schema.rules, schema.err
+	}
+
+	columns, rules, err := p.compileType(itemType)
+	cached, _ := schemaCache.LoadOrStore(key, &cachedSchema{columns: columns, rules: rules, err: err})
+	schema := cached.(*cachedSchema)
+	return schema.columns, schema.rules, schema.err
+}
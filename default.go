@@ -0,0 +1,77 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultFunc returns default cell value, it is used by "default:@name" tag
+type DefaultFunc func() interface{}
+
+// WithDefaultFunc set default func, "now" is registered by default
+func WithDefaultFunc(name string, fn DefaultFunc) Option {
+	return func(o *options) {
+		o.defaultFuncs[name] = fn
+	}
+}
+
+func builtinDefaultFuncs() map[string]DefaultFunc {
+	return map[string]DefaultFunc{
+		"now": func() interface{} { return time.Now() },
+	}
+}
+
+// unwrapValue dereferences pointer and nullable struct, nil and null are
+// returned as invalid Value. t is type of unwrapped value, explicit reports
+// the value was set through pointer or nullable.
+func unwrapValue(v reflect.Value) (val reflect.Value, t reflect.Type, explicit bool) {
+	if !v.IsValid() {
+		return v, nil, false
+	}
+
+	t = v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsNil() {
+			return reflect.Value{}, t, false
+		}
+		v = v.Elem()
+		explicit = true
+	}
+
+	if value, _, ok := nullableFields(t); ok {
+		t = t.Field(value).Type
+		v, _ = nullValue(v)
+		explicit = v.IsValid()
+	}
+
+	return v, t, explicit
+}
+
+// defaultValue returns default of column converted to type t, function
+// default "@name" is called, "@@" escapes literal "@". Default that can't
+// be converted is kept as text.
+func (em *ExcelizeMapper) defaultValue(column Column, t reflect.Type) (reflect.Value, error) {
+	def := column.DefaultValue
+	if strings.HasPrefix(def, "@@") {
+		def = def[1:]
+	} else if name, ok := strings.CutPrefix(def, "@"); ok {
+		fn, ok := em.options.defaultFuncs[name]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("default func %q not found", name)
+		}
+		if val := fn(); val != nil {
+			return reflect.ValueOf(val), nil
+		}
+		return reflect.ValueOf(""), nil
+	}
+
+	if t != nil && t.Kind() != reflect.String && t.Kind() != reflect.Interface {
+		if v, err := convertString(def, t); err == nil {
+			return v, nil
+		}
+	}
+	return reflect.ValueOf(def), nil
+}
@@ -0,0 +1,119 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	aggregateFirst = "first"
+	aggregateLast  = "last"
+	aggregateSum   = "sum"
+	aggregateMin   = "min"
+	aggregateMax   = "max"
+	aggregateJoin  = "join"
+	aggregateError = "error"
+
+	defaultJoinSeparator = ", "
+)
+
+type aggregatePolicy struct {
+	mode      string
+	separator string
+}
+
+/*
+parseAggregatePolicy parses value of the dynamic "aggregate" tag, it decides
+what to write when several entries of one row render the same header.
+
+	aggregate:last       last entry wins, default
+	aggregate:first      first entry wins
+	aggregate:sum        sum of numeric values
+	aggregate:min        minimal value
+	aggregate:max        maximal value
+	aggregate:join       values joined by ", "
+	aggregate:join( | )  values joined by " | "
+	aggregate:error      SetData returns error
+*/
+func parseAggregatePolicy(rule string) (aggregatePolicy, error) {
+	rule = strings.TrimSpace(rule)
+	switch rule {
+	case "":
+		return aggregatePolicy{mode: aggregateLast}, nil
+	case aggregateFirst, aggregateLast, aggregateSum, aggregateMin, aggregateMax, aggregateError:
+		return aggregatePolicy{mode: rule}, nil
+	case aggregateJoin:
+		return aggregatePolicy{mode: aggregateJoin, separator: defaultJoinSeparator}, nil
+	}
+
+	if strings.HasPrefix(rule, aggregateJoin+"(") && strings.HasSuffix(rule, ")") {
+		return aggregatePolicy{
+			mode:      aggregateJoin,
+			separator: rule[len(aggregateJoin)+1 : len(rule)-1],
+		}, nil
+	}
+
+	return aggregatePolicy{}, fmt.Errorf("unknown aggregate policy %q", rule)
+}
+
+// merge combines value already stored for header with next one, nil values
+// are treated as missing.
+func (ap aggregatePolicy) merge(current, next interface{}) (interface{}, error) {
+	switch ap.mode {
+	case aggregateFirst:
+		return current, nil
+	case aggregateLast:
+		return next, nil
+	case aggregateError:
+		return nil, fmt.Errorf("duplicate dynamic header")
+	}
+
+	if current == nil {
+		return next, nil
+	}
+	if next == nil {
+		return current, nil
+	}
+
+	switch ap.mode {
+	case aggregateSum:
+		return sumValues(current, next)
+	case aggregateMin:
+		if compareValues(next, current) < 0 {
+			return next, nil
+		}
+		return current, nil
+	case aggregateMax:
+		if compareValues(next, current) > 0 {
+			return next, nil
+		}
+		return current, nil
+	case aggregateJoin:
+		return fmt.Sprint(current) + ap.separator + fmt.Sprint(next), nil
+	}
+
+	return next, nil
+}
+
+// sumValues sums numbers, sum of values of the same type keeps the type,
+// so formatters of named numeric types still apply.
+func sumValues(a, b interface{}) (interface{}, error) {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	var sum reflect.Value
+	switch {
+	case isInt(av) && isInt(bv):
+		sum = reflect.ValueOf(av.Int() + bv.Int())
+	case isUint(av) && isUint(bv):
+		sum = reflect.ValueOf(av.Uint() + bv.Uint())
+	case isNumber(av) && isNumber(bv):
+		sum = reflect.ValueOf(toFloat(av) + toFloat(bv))
+	default:
+		return nil, fmt.Errorf("cannot sum %T and %T", a, b)
+	}
+
+	if av.Type() == bv.Type() {
+		sum = sum.Convert(av.Type())
+	}
+	return sum.Interface(), nil
+}
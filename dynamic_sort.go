@@ -0,0 +1,235 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DynamicComparator compares two dynamic headers, it returns a negative
+// number when a < b, a positive number when a > b and zero otherwise.
+type DynamicComparator func(a, b DynamicHeader) int
+
+const (
+	dynamicSortAppearance = ""
+	dynamicSortLexical    = "lexical"
+	dynamicSortNatural    = "natural"
+	dynamicSortPos        = "pos"
+)
+
+type dynamicSortKey struct {
+	field string
+	desc  bool
+}
+
+/*
+parseDynamicSort parses value of the dynamic "sort" tag.
+
+	sort:lexical            by header text
+	sort:natural            by header text, digit runs compared as numbers
+	sort:pos                by dynamicpos values in declaration order
+	sort:pos(Year,-Quarter) by listed dynamicpos fields, "-" for descending
+	sort:name               by comparator registered with WithDynamicComparator
+
+Without the tag headers keep the order they first appear in data.
+*/
+func parseDynamicSort(rule string, posFields []string) (string, []dynamicSortKey, error) {
+	rule = strings.TrimSpace(rule)
+	switch rule {
+	case dynamicSortAppearance, dynamicSortLexical, dynamicSortNatural:
+		return rule, nil, nil
+	}
+
+	if !strings.HasPrefix(rule, dynamicSortPos) {
+		return rule, nil, nil
+	}
+
+	args := strings.TrimPrefix(rule, dynamicSortPos)
+	if args == "" {
+		keys := make([]dynamicSortKey, 0, len(posFields))
+		for _, field := range posFields {
+			keys = append(keys, dynamicSortKey{field: field})
+		}
+		return dynamicSortPos, keys, nil
+	}
+
+	if !strings.HasPrefix(args, "(") || !strings.HasSuffix(args, ")") {
+		// e.g. custom comparator named "position"
+		return rule, nil, nil
+	}
+
+	var keys []dynamicSortKey
+	for _, name := range strings.Split(args[1:len(args)-1], ",") {
+		name = strings.TrimSpace(name)
+		key := dynamicSortKey{field: strings.TrimPrefix(name, "-"), desc: strings.HasPrefix(name, "-")}
+		found := false
+		for _, field := range posFields {
+			if field == key.field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", nil, fmt.Errorf("unknown dynamicpos field %q in sort rule %q", key.field, rule)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return "", nil, fmt.Errorf("empty sort rule %q", rule)
+	}
+
+	return dynamicSortPos, keys, nil
+}
+
+// comparator returns nil when headers keep the order of appearance.
+func (dr *DynamicRules) comparator(comparators map[string]DynamicComparator) (DynamicComparator, error) {
+	switch dr.sortMode {
+	case dynamicSortAppearance:
+		return nil, nil
+	case dynamicSortLexical:
+		return func(a, b DynamicHeader) int {
+			return strings.Compare(a.id(), b.id())
+		}, nil
+	case dynamicSortNatural:
+		return func(a, b DynamicHeader) int {
+			return naturalCompare(a.id(), b.id())
+		}, nil
+	case dynamicSortPos:
+		keys := dr.sortKeys
+		return func(a, b DynamicHeader) int {
+			for _, key := range keys {
+				c := compareValues(a.Key[key.field], b.Key[key.field])
+				if key.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		}, nil
+	}
+
+	cmp, ok := comparators[dr.sortMode]
+	if !ok {
+		return nil, fmt.Errorf("dynamic field %s: comparator %q not found", dr.ParentFieldName, dr.sortMode)
+	}
+	return cmp, nil
+}
+
+// compareValues compares numbers by value, time.Time chronologically and
+// everything else by natural order of its text. nil sorts first.
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isInt(av) && isInt(bv):
+		return compareOrdered(av.Int(), bv.Int())
+	case isUint(av) && isUint(bv):
+		return compareOrdered(av.Uint(), bv.Uint())
+	case isNumber(av) && isNumber(bv):
+		return compareOrdered(toFloat(av), toFloat(bv))
+	case av.Kind() == reflect.Bool && bv.Kind() == reflect.Bool:
+		return compareOrdered(boolToInt(av.Bool()), boolToInt(bv.Bool()))
+	}
+
+	return naturalCompare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered[T int64 | uint64 | float64 | int](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isInt(v reflect.Value) bool {
+	return isIntKind(v.Kind())
+}
+
+func isUint(v reflect.Value) bool {
+	return isUintKind(v.Kind())
+}
+
+func isNumber(v reflect.Value) bool {
+	return isInt(v) || isUint(v) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v):
+		return float64(v.Int())
+	case isUint(v):
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// naturalCompare compares strings with runs of digits compared by their
+// numeric value, so "Q2" < "Q10".
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		ad, bd := isDigit(a[0]), isDigit(b[0])
+		if ad && bd {
+			var an, bn string
+			an, a = splitDigits(a)
+			bn, b = splitDigits(b)
+			at, bt := strings.TrimLeft(an, "0"), strings.TrimLeft(bn, "0")
+			if c := compareOrdered(len(at), len(bt)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(at, bt); c != 0 {
+				return c
+			}
+			if c := compareOrdered(len(an), len(bn)); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if c := compareOrdered(int(a[0]), int(b[0])); c != 0 {
+			return c
+		}
+		a, b = a[1:], b[1:]
+	}
+	return compareOrdered(len(a), len(b))
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -143,6 +143,7 @@ type User2 struct {
 	Desc string    `excelize-mapper:"header:Desc;"`
 	Sex  Sex       `excelize-mapper:"header:Sex;format:sex;"`
 	Arr  []int     `excelize-mapper:"header:Arr;format:slice;"`
+	Tags MyArr     `excelize-mapper:"header:Tags;"`
 	Time time.Time `excelize-mapper:"header:Time;format:time;"`
 }
 
@@ -170,6 +171,7 @@ func example2() {
 		Desc:  "This is a long text, it will be wrapped.",
 		Sex:   SexMale,
 		Arr:   []int{1, 23, 45},
+		Tags:  MyArr{4, 5},
 		Time:  time.Now(),
 	}, {
 		IdInt: IdInt{Id: 2},
@@ -177,6 +179,7 @@ func example2() {
 		Desc:  "This is a long text.",
 		Sex:   SexFemale,
 		Arr:   []int{1, 23, 0},
+		Tags:  MyArr{6},
 		Time:  time.Now(),
 	}}
 
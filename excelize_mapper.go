@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 )
@@ -21,6 +22,13 @@ const (
 	defaultTagDynamicKey    = "dynamic"
 	defaultTagDynamicPosKey = "dynamicpos"
 	defaultTagDynamicValKey = "dynamicval"
+	defaultTagSortKey       = "sort"
+	defaultTagAggregateKey  = "aggregate"
+	defaultTagNumFmtKey     = "numfmt"
+	defaultTagStyleKey      = "style"
+	defaultTagAfterKey      = "after"
+	defaultTagViewsKey      = "views"
+	defaultTagOmitEmptyKey  = "omitempty"
 )
 
 type ExcelizeMapper struct {
@@ -33,12 +41,39 @@ func NewExcelizeMapper(opts ...Option) ExcelizeMapper {
 		tagKey:       defaultTagKey,
 		autoSort:     true,
 		formatterMap: make(map[string]Format, 0),
+		contextMap:   make(map[string]ContextFormat, 0),
+		factories:    builtinFactories(),
+		typeFormats:  make(map[reflect.Type]Format, 0),
+		typeParsers:  make(map[reflect.Type]Parse, 0),
+		defaultFuncs: builtinDefaultFuncs(),
+		factoryCache: newFactoryCache(),
+		parserMap:    make(map[string]Parse, 0),
+		comparators:  make(map[string]DynamicComparator, 0),
+
+		dynamicDomains: make(map[string]func() []DynamicKey, 0),
+		dynamicFills:   make(map[string]interface{}, 0),
+		styles:         make(map[string]*excelize.Style, 0),
+		schemas:        make(map[reflect.Type]schemaProvider, 0),
 	}
 
 	for _, opt := range opts {
 		opt(&op)
 	}
 
+	return newExcelizeMapper(op)
+}
+
+// With returns copy of mapper with extra options, e.g. to select view per call
+func (em *ExcelizeMapper) With(opts ...Option) ExcelizeMapper {
+	op := em.options.clone()
+	for _, opt := range opts {
+		opt(&op)
+	}
+
+	return newExcelizeMapper(op)
+}
+
+func newExcelizeMapper(op options) ExcelizeMapper {
 	return ExcelizeMapper{
 		options: op,
 		parser: parser{
@@ -53,174 +88,483 @@ func NewExcelizeMapper(opts ...Option) ExcelizeMapper {
 			tagDynamicKey:    defaultTagDynamicKey,
 			tagDynamicPosKey: defaultTagDynamicPosKey,
 			tagDynamicValKey: defaultTagDynamicValKey,
+			tagSortKey:       defaultTagSortKey,
+			tagAggregateKey:  defaultTagAggregateKey,
+			tagNumFmtKey:     defaultTagNumFmtKey,
+			tagStyleKey:      defaultTagStyleKey,
+			tagAfterKey:      defaultTagAfterKey,
+			tagViewsKey:      defaultTagViewsKey,
+			tagOmitEmptyKey:  defaultTagOmitEmptyKey,
+			strict:           op.strictTags,
 		},
 	}
 }
 
 // Already know data is slice
-func (em *ExcelizeMapper) parseSlice(rules *DynamicRules, model interface{}) []string {
-	var headers []string
-	modelValue := reflect.ValueOf(model)
+func (em *ExcelizeMapper) parseSlice(rules *DynamicRules, modelValue reflect.Value) ([]DynamicHeader, error) {
+	// interface entries are not resolved when there are none
+	if rules.entryType == nil {
+		return nil, nil
+	}
+	if provider, ok := em.options.dynamicDomains[rules.ParentFieldName]; ok {
+		return em.parseDomain(rules, provider())
+	}
+
+	var headers []DynamicHeader
+	seen := make(map[string]bool)
 	for i := 0; i < modelValue.Len(); i++ {
 
-		modelEntry := modelValue.Index(i)
-		sliceEntries := modelEntry.FieldByName(rules.ParentFieldName)
+		modelEntry := indirectValue(modelValue.Index(i))
+		if !modelEntry.IsValid() {
+			continue
+		}
 
-		for j := 0; j < sliceEntries.Len(); j++ {
-			entryVal := sliceEntries.Index(j)
+		sliceEntries, err := rules.getEntries(modelEntry)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+2, err)
+		}
 
-			header := rules.getReplacedHeader(entryVal)
+		for _, entryVal := range sliceEntries {
+			header, err := rules.getReplacedHeader(entryVal, em.lookupFormatter)
+			if err != nil {
+				return nil, err
+			}
 
-			if !slices.Contains(headers, header) {
+			if id := header.id(); !seen[id] {
+				seen[id] = true
 				headers = append(headers, header)
 			}
 		}
 
 	}
 
-	return headers
+	cmp, err := rules.comparator(em.options.comparators)
+	if err != nil {
+		return nil, err
+	}
+	if cmp != nil {
+		slices.SortStableFunc(headers, cmp)
+	} else if len(rules.groups) > 0 {
+		groupByAppearance(headers)
+	}
+
+	return headers, nil
 }
 
-func (em *ExcelizeMapper) foreachValues(rules *DynamicRules, modelValue reflect.Value, cb func(string, any)) {
+// groupByAppearance keeps headers of same group adjacent so that group cells
+// can be merged, groups keep order they first appear in.
+func groupByAppearance(headers []DynamicHeader) {
+	first := make(map[string]int)
+	for i, header := range headers {
+		for level := range header.Groups {
+			prefix := strings.Join(header.Groups[:level+1], headerLevelSep)
+			if _, ok := first[prefix]; !ok {
+				first[prefix] = i
+			}
+		}
+	}
 
-	sliceEntries := modelValue.FieldByName(rules.ParentFieldName)
+	slices.SortStableFunc(headers, func(a, b DynamicHeader) int {
+		for level := range a.Groups {
+			ai := first[strings.Join(a.Groups[:level+1], headerLevelSep)]
+			bi := first[strings.Join(b.Groups[:level+1], headerLevelSep)]
+			if ai != bi {
+				return ai - bi
+			}
+		}
+		return 0
+	})
+}
+
+// parseDomain renders headers of declared domain keeping its order
+func (em *ExcelizeMapper) parseDomain(rules *DynamicRules, domain []DynamicKey) ([]DynamicHeader, error) {
+	headers := make([]DynamicHeader, 0, len(domain))
+	seen := make(map[string]bool, len(domain))
+	for _, key := range domain {
+		header, err := rules.renderHeader(key, em.lookupFormatter)
+		if err != nil {
+			return nil, err
+		}
+
+		if id := header.id(); !seen[id] {
+			seen[id] = true
+			headers = append(headers, header)
+		}
+	}
+
+	return headers, nil
+}
+
+func (em *ExcelizeMapper) foreachValues(rules *DynamicRules, modelValue reflect.Value, cb func(string, any) error) error {
+
+	sliceEntries, err := rules.getEntries(modelValue)
+	if err != nil {
+		return err
+	}
 	slog.Debug("modelValue",
-		"kind", sliceEntries.Type().Kind().String(),
+		"entries", len(sliceEntries),
 		"name", modelValue.Type().Name())
 
-	for j := 0; j < sliceEntries.Len(); j++ {
-		entry := sliceEntries.Index(j)
+	for _, entry := range sliceEntries {
 		slog.Debug("entryVal", "name", entry.Type().Name())
 
-		header := rules.getReplacedHeader(entry)
-		slog.Debug("getReplacedHeader", "name", header)
+		header, err := rules.getReplacedHeader(entry, em.lookupFormatter)
+		if err != nil {
+			return err
+		}
 		slog.Debug("ValueField", "name", rules.ValueField)
 
-		val := entry.FieldByName(rules.ValueField)
+		val := rules.getValue(entry)
 		slog.Debug("val", "value", val)
 
-		if val.Kind() == reflect.Ptr {
-			if val.IsNil() {
-				cb(header, "")
-			} else {
-				cb(header, val.Elem().Interface())
-			}
+		if val = indirectValue(val); val.IsValid() {
+			err = cb(header.id(), val.Interface())
 		} else {
-			cb(header, val.Interface())
+			err = cb(header.id(), nil)
+		}
+		if err != nil {
+			return err
 		}
 	}
+
+	return nil
 }
 
 func (em *ExcelizeMapper) SetData(f *excelize.File, sheet string, slice interface{}) error {
-	columns, dynamicRules, err := em.parser.parse(slice)
+	columns, dynamicRules, err := em.parse(slice)
+	if err != nil {
+		return err
+	}
+
+	return em.setData(f, sheet, columns, dynamicRules, indirectValue(reflect.ValueOf(slice)))
+}
+
+// setData writes rows of slice di by parsed schema
+func (em *ExcelizeMapper) setData(f *excelize.File, sheet string, columns []Column, dynamicRules []*DynamicRules, di reflect.Value) error {
+	rowType, err := rowTypeOf(di)
+	if err != nil {
+		return err
+	}
+
+	dynamicRules, err = resolveEntryTypes(dynamicRules, di, rowType)
 	if err != nil {
 		return err
 	}
+	columns, dynamicRules = em.selectColumns(columns, dynamicRules)
 
-	headers := make([]string, 0, len(columns))
-	currentIndex := 0
-	for _, column := range columns {
-		for ; currentIndex < column.ColumnIndex; currentIndex++ {
-			headers = append(headers, "")
+	// Handle dynamic fields headers
+	dynamicHeaders := make([][]DynamicHeader, len(dynamicRules))
+	// position of every dynamic header by its id
+	dynamicPos := make([]map[string]int, len(dynamicRules))
+	dynamicSizes := make([]int, len(dynamicRules))
+	headerRows := 1
+	for i, rules := range dynamicRules {
+		dynamicHeaders[i], err = em.parseSlice(rules, di)
+		if err != nil {
+			return err
 		}
+		dynamicPos[i] = make(map[string]int, len(dynamicHeaders[i]))
+		for j, header := range dynamicHeaders[i] {
+			dynamicPos[i][header.id()] = j
+		}
+		dynamicSizes[i] = len(dynamicHeaders[i])
+		headerRows = max(headerRows, rules.Levels())
+	}
 
-		headers = append(headers, column.HeaderName)
-		currentIndex = column.ColumnIndex + 1
+	layout, err := newColumnLayout(columns, dynamicRules, dynamicSizes)
+	if err != nil {
+		return err
 	}
 
-	for _, column := range columns {
-		width := em.options.defaultWidth
-		if column.ColumnWidth > 0 {
-			width = column.ColumnWidth
+	for i, column := range columns {
+		if err := em.setColumnWidth(f, sheet, layout.static[i], column); err != nil {
+			return err
 		}
-		if width > 0 {
-			colName, err := excelize.ColumnNumberToName(column.ColumnIndex + 1)
-			if err != nil {
-				return fmt.Errorf("excelize ColumnNumberToName error: %w", err)
+	}
+	for i, rules := range dynamicRules {
+		for j := range dynamicHeaders[i] {
+			if err := em.setColumnWidth(f, sheet, layout.dynamic[i]+j, rules.Value); err != nil {
+				return err
 			}
-			f.SetColWidth(sheet, colName, colName, width)
 		}
 	}
 
-	// Handle dynamic fields headers
-	var dynamicHeaders []string
+	if err := em.setHeaders(f, sheet, headerRows, layout, columns, dynamicRules, dynamicHeaders); err != nil {
+		return err
+	}
 
-	if dynamicRules != nil {
-		dynamicHeaders = em.parseSlice(dynamicRules, slice)
-		if dynamicHeaders != nil {
-			headers = append(headers, dynamicHeaders...)
+	// number format of cells with fraction, e.g. of "thousands"
+	staticFractions := make([]string, len(columns))
+	for i, column := range columns {
+		if staticFractions[i], err = em.fractionNumFmt(column); err != nil {
+			return err
 		}
 	}
-
-	err = f.SetSheetRow(sheet, "A1", &headers)
-	if err != nil {
-		return fmt.Errorf("excelize SetSheetRow error: %w", err)
+	dynamicFractions := make([]string, len(dynamicRules))
+	for i, rules := range dynamicRules {
+		if dynamicFractions[i], err = em.fractionNumFmt(rules.Value); err != nil {
+			return err
+		}
 	}
+	var fractions []fractionCell
 
-	di := reflect.Indirect(reflect.ValueOf(slice))
 	for rowIndex := 0; rowIndex < di.Len(); rowIndex++ {
-		rowVal := reflect.Indirect(di.Index(rowIndex))
-		vals := make([]interface{}, 0, len(columns))
+		rowVal := indirectValue(di.Index(rowIndex))
+		// nil rows are left blank
+		if !rowVal.IsValid() {
+			continue
+		}
+		if rowVal.Type() != rowType {
+			return fmt.Errorf("row %d: unexpected type %s, want %s", rowIndex+headerRows+1, rowVal.Type(), rowType)
+		}
+		vals := make([]interface{}, layout.width)
 
-		currentIndex = 0
-		for _, column := range columns {
-			for ; currentIndex < column.ColumnIndex; currentIndex++ {
-				vals = append(vals, "")
+		for i, column := range columns {
+			at := cellContext{row: rowVal, rowIndex: rowIndex, col: layout.static[i], sheetRow: rowIndex + headerRows}
+			vals[layout.static[i]], err = em.cellValue(column, column.fieldValue(rowVal), at)
+			if err != nil {
+				return cellError(at.col, at.sheetRow, err)
 			}
+			fractions = appendFraction(fractions, staticFractions[i], column, at.col, at.sheetRow, vals[at.col])
+		}
 
-			fieldValue := getNestedFieldValue(rowVal, column.FieldName)
-
-			if fieldValue.Kind() == reflect.Ptr {
-				if fieldValue.IsNil() {
-					fieldValue = reflect.ValueOf("")
-				} else {
-					fieldValue = fieldValue.Elem()
-				}
-			} else if fieldValue.IsZero() && column.DefaultValue != "" {
-				fieldValue = reflect.ValueOf(column.DefaultValue)
+		// Handle dynamic fields values
+		for i, rules := range dynamicRules {
+			if len(dynamicHeaders[i]) == 0 {
+				continue
 			}
 
-			if format, ok := em.options.formatterMap[column.FormatterKey]; ok {
-				formatVal := format(fieldValue.Interface())
-				fieldValue = reflect.ValueOf(formatVal)
+			dynamicVals := vals[layout.dynamic[i] : layout.dynamic[i]+dynamicSizes[i]]
+			if fill, ok := em.options.dynamicFills[rules.ParentFieldName]; ok {
+				for j := range dynamicVals {
+					dynamicVals[j] = fill
+				}
 			}
 
-			vals = append(vals, fieldValue.Interface())
+			seen := make([]bool, dynamicSizes[i])
+			err := em.foreachValues(rules, rowVal, func(niddle string, val any) error {
+				pos, ok := dynamicPos[i][niddle]
+				// entry outside of declared domain
+				if !ok {
+					return nil
+				}
 
-			currentIndex = column.ColumnIndex + 1
-		}
+				if !seen[pos] {
+					seen[pos] = true
+					dynamicVals[pos] = val
+					return nil
+				}
 
-		// Handle dynamic fields values
-		if len(dynamicHeaders) > 0 {
-			dynamicVals := make([]interface{}, len(dynamicHeaders))
-
-			em.foreachValues(dynamicRules, rowVal, func(niddle string, val any) {
-				pos := slices.IndexFunc(dynamicHeaders, func(header string) bool {
-					return header == niddle
-				})
-				dynamicVals[pos] = val
+				merged, err := rules.aggregate.merge(dynamicVals[pos], val)
+				if err != nil {
+					return fmt.Errorf("row %d: dynamic field %s: header %q: %w",
+						rowIndex+headerRows+1, rules.ParentFieldName, dynamicHeaders[i][pos].Header, err)
+				}
+				dynamicVals[pos] = merged
+				return nil
 			})
+			if err != nil {
+				return err
+			}
 
-			vals = append(vals, dynamicVals...)
+			for j, val := range dynamicVals {
+				if !seen[j] {
+					continue
+				}
+				at := cellContext{row: rowVal, rowIndex: rowIndex, col: layout.dynamic[i] + j, sheetRow: rowIndex + headerRows}
+				dynamicVals[j], err = em.cellValue(rules.Value, reflect.ValueOf(val), at)
+				if err != nil {
+					return cellError(at.col, at.sheetRow, err)
+				}
+				fractions = appendFraction(fractions, dynamicFractions[i], rules.Value, at.col, at.sheetRow, dynamicVals[j])
+			}
 		}
 
-		cell, err := excelize.CoordinatesToCellName(1, rowIndex+2)
+		cell, err := excelize.CoordinatesToCellName(1, rowIndex+headerRows+1)
 		if err != nil {
 			return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
 		}
 
+		richTexts := takeRichTexts(vals)
+
 		err = f.SetSheetRow(sheet, cell, &vals)
 		if err != nil {
 			return fmt.Errorf("excelize SetSheetRow error: %w", err)
 		}
+
+		for col, runs := range richTexts {
+			if err := setRichText(f, sheet, col, rowIndex+headerRows, runs); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Handle cell styles
+	styles := make(map[cellStyleKey]int)
+	rowCount := di.Len()
+	for i, column := range columns {
+		if err := em.setColumnStyle(f, sheet, layout.static[i], headerRows, rowCount, column, styles); err != nil {
+			return err
+		}
+	}
+	for i, rules := range dynamicRules {
+		for j := range dynamicHeaders[i] {
+			if err := em.setColumnStyle(f, sheet, layout.dynamic[i]+j, headerRows, rowCount, rules.Value, styles); err != nil {
+				return err
+			}
+		}
+	}
+
+	return em.setFractionStyles(f, sheet, fractions, styles)
+}
+
+// blankZero reports whether zero values of column are written as empty cells
+func (em *ExcelizeMapper) blankZero(column Column) bool {
+	return column.OmitEmpty || em.options.blankZero
+}
+
+// isZeroValue reports whether v is zero, time.Time is zero by its IsZero
+func isZeroValue(v reflect.Value) bool {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.IsZero()
+	}
+	return v.IsZero()
+}
+
+// cellContext locates cell of value, zero based
+type cellContext struct {
+	row      reflect.Value
+	rowIndex int
+	col      int
+	sheetRow int
+}
+
+// cellValue converts field value to value of cell, invalid value is treated as nil pointer
+func (em *ExcelizeMapper) cellValue(column Column, fieldValue reflect.Value, at cellContext) (interface{}, error) {
+	// default is used for nil, null and zero value, not for zero set through pointer
+	value, valueType, explicit := unwrapValue(fieldValue)
+	switch {
+	case column.DefaultValue != "" && (!value.IsValid() || !explicit && value.IsZero()):
+		def, err := em.defaultValue(column, valueType)
+		if err != nil {
+			return nil, err
+		}
+		fieldValue = def
+	case !value.IsValid():
+		fieldValue = reflect.ValueOf("")
+	case !explicit && em.blankZero(column) && isZeroValue(value):
+		// blank zero skips formatters, they expect value of field type
+		return "", nil
+	default:
+		fieldValue = value
+	}
+
+	if format, ok := em.options.contextMap[column.FormatterKey]; ok {
+		cell, err := excelize.CoordinatesToCellName(at.col+1, at.sheetRow+1)
+		if err != nil {
+			return nil, fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+		}
+		return format(FormatContext{
+			Row:      at.row.Interface(),
+			Value:    fieldValue.Interface(),
+			Column:   column,
+			RowIndex: at.rowIndex,
+			Cell:     cell,
+		})
+	}
+
+	if column.FormatterKey == "" {
+		if format, ok := em.options.typeFormats[fieldValue.Type()]; ok {
+			return format(fieldValue.Interface()), nil
+		}
+		if marshal := column.marshalerOf(fieldValue.Type()); marshal != nil {
+			return marshal(fieldValue)
+		}
+	}
+
+	number, err := em.numberFormatter(column.FormatterKey)
+	if err != nil {
+		return nil, err
+	}
+	if number != nil {
+		return number.value(fieldValue.Interface()), nil
+	}
+
+	format, ok, err := em.lookupFormatter(column.FormatterKey)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		formatVal := format(fieldValue.Interface())
+		fieldValue = reflect.ValueOf(formatVal)
+	}
+
+	return nativeValue(fieldValue), nil
+}
+
+// basicTypes are predeclared types by kind
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// nativeValue converts named basic types without marshal interfaces, e.g.
+// integer enums, to their predeclared type, so they are written as numbers.
+func nativeValue(v reflect.Value) interface{} {
+	if t, ok := basicTypes[v.Kind()]; ok && v.Type() != t {
+		return v.Convert(t).Interface()
+	}
+	return v.Interface()
+}
+
+// takeRichTexts removes rich text values from row, SetSheetRow can't write them
+func takeRichTexts(vals []interface{}) map[int][]excelize.RichTextRun {
+	var richTexts map[int][]excelize.RichTextRun
+	for i, val := range vals {
+		var runs []excelize.RichTextRun
+		switch v := val.(type) {
+		case []excelize.RichTextRun:
+			runs = v
+		case excelize.RichTextRun:
+			runs = []excelize.RichTextRun{v}
+		default:
+			continue
+		}
+		if richTexts == nil {
+			richTexts = make(map[int][]excelize.RichTextRun)
+		}
+		richTexts[i] = runs
+		vals[i] = nil
 	}
+	return richTexts
+}
 
+func setRichText(f *excelize.File, sheet string, col, rowIndex int, runs []excelize.RichTextRun) error {
+	cell, err := excelize.CoordinatesToCellName(col+1, rowIndex+1)
+	if err != nil {
+		return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+	}
+	if err := f.SetCellRichText(sheet, cell, runs); err != nil {
+		return fmt.Errorf("excelize SetCellRichText error: %w", err)
+	}
 	return nil
 }
 
-func getNestedFieldValue(v reflect.Value, fieldPath string) reflect.Value {
-	parts := strings.Split(fieldPath, ".")
-	for _, part := range parts {
+// fieldByIndex returns field by index path, nil pointers on the way give zero value
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
 		if v.Kind() == reflect.Ptr {
 			if v.IsNil() {
 				v = reflect.Zero(v.Type().Elem())
@@ -228,7 +572,94 @@ func getNestedFieldValue(v reflect.Value, fieldPath string) reflect.Value {
 				v = v.Elem()
 			}
 		}
-		v = v.FieldByName(part)
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		v = v.Field(i)
 	}
 	return v
 }
+
+// indirectValue follows pointers and interfaces, it returns invalid value for nil
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// rowTypeOf returns struct type of rows, interface rows are resolved by first non-nil row
+func rowTypeOf(data reflect.Value) (reflect.Type, error) {
+	itemType := data.Type().Elem()
+	for itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+
+	if itemType.Kind() == reflect.Interface {
+		for i := 0; i < data.Len(); i++ {
+			if row := indirectValue(data.Index(i)); row.IsValid() {
+				itemType = row.Type()
+				break
+			}
+		}
+		if itemType.Kind() == reflect.Interface {
+			return nil, fmt.Errorf("cannot resolve row type of %s without non-nil rows", data.Type())
+		}
+	}
+
+	if itemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("data item %s is not struct", itemType)
+	}
+
+	return itemType, nil
+}
+
+// resolveEntryTypes replaces rules of interface entries by rules compiled for
+// type of their first non-nil entry, rules without entries are kept.
+func resolveEntryTypes(dynamicRules []*DynamicRules, data reflect.Value, rowType reflect.Type) ([]*DynamicRules, error) {
+	var resolved []*DynamicRules
+	for i, rules := range dynamicRules {
+		if rules.resolveEntry == nil {
+			continue
+		}
+		entryType := firstEntryType(rules, data, rowType)
+		if entryType == nil {
+			continue
+		}
+		compiled, err := rules.resolveEntry(entryType)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == nil {
+			resolved = slices.Clone(dynamicRules)
+		}
+		resolved[i] = compiled
+	}
+	if resolved == nil {
+		return dynamicRules, nil
+	}
+	return resolved, nil
+}
+
+// firstEntryType returns type of the first non-nil entry of dynamic field, nil if there is none
+func firstEntryType(rules *DynamicRules, data reflect.Value, rowType reflect.Type) reflect.Type {
+	for i := 0; i < data.Len(); i++ {
+		row := indirectValue(data.Index(i))
+		if !row.IsValid() || row.Type() != rowType {
+			continue
+		}
+		entries := indirectValue(fieldByIndex(row, rules.fieldIndex))
+		if !entries.IsValid() || entries.Kind() != reflect.Slice && entries.Kind() != reflect.Array {
+			continue
+		}
+		for j := 0; j < entries.Len(); j++ {
+			if entry := indirectValue(entries.Index(j)); entry.IsValid() {
+				return entry.Type()
+			}
+		}
+	}
+	return nil
+}
@@ -1,8 +1,13 @@
 package excelizemapper
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -232,3 +237,970 @@ func TestDynamicSetData(t *testing.T) {
 
 	f.SaveAs("./testData/dynamic.xlsx")
 }
+
+type embeddedDynamic struct {
+	Dynamic []DynamicEntry `excelize-mapper:"dynamic:$1/$2"`
+}
+
+type nestedDynamic struct {
+	embeddedDynamic
+}
+
+type embeddedDynamicModel struct {
+	Text string `excelize-mapper:"header:Text"`
+	nestedDynamic
+}
+
+func TestEmbeddedDynamicSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []embeddedDynamicModel{{
+		Text: "text1",
+		nestedDynamic: nestedDynamic{embeddedDynamic{Dynamic: []DynamicEntry{
+			{Year: 2021, Quarter: 1, Value: floatPtr(1)},
+			{Year: 2021, Quarter: 2, Value: floatPtr(2)},
+		}}},
+	}}
+
+	mapper := NewExcelizeMapper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"Text", "2021/1", "2021/2"},
+		{"text1", "1", "2"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+type namedDynamicEntry struct {
+	Year    int     `excelize-mapper:"dynamicpos:"`
+	Quarter int     `excelize-mapper:"dynamicpos:q"`
+	Value   float64 `excelize-mapper:"dynamicval:"`
+}
+
+type namedDynamicModel struct {
+	Text    string              `excelize-mapper:"header:Text"`
+	Dynamic []namedDynamicEntry `excelize-mapper:"dynamic:{Year:%05d}/{q|roman} {{Q{Quarter}}}"`
+}
+
+func TestNamedDynamicHeaders(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []namedDynamicModel{{
+		Text: "text1",
+		Dynamic: []namedDynamicEntry{
+			{Year: 2021, Quarter: 1, Value: 1},
+			{Year: 2021, Quarter: 2, Value: 2},
+		},
+	}}
+
+	roman := func(v interface{}) string {
+		return []string{"", "I", "II", "III", "IV"}[v.(int)]
+	}
+	mapper := NewExcelizeMapper(WithFormatter("roman", roman))
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Text", "02021/I {Q1}", "02021/II {Q2}"}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("headers = %v, want %v", rows[0], want)
+	}
+}
+
+func TestHeaderTemplateLegacyPlaceholders(t *testing.T) {
+	mappings := map[string]string{"$1": "A", "$10": "B"}
+	tpl, err := compileHeaderTemplate("$1-$10", legacyKeys("$1-$10", mappings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tpl.resolve(mappings); err != nil {
+		t.Fatal(err)
+	}
+
+	key := DynamicKey{"A": 1, "B": 10}
+	for i := 0; i < 10; i++ {
+		header, err := tpl.render(key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "1-10" {
+			t.Fatalf("header = %q, want %q", header, "1-10")
+		}
+	}
+
+	for _, rule := range []string{"{Year", "Year}", "{}", "{Year|}"} {
+		if _, err := compileHeaderTemplate(rule, nil); err == nil {
+			t.Errorf("compileHeaderTemplate(%q) expected error", rule)
+		}
+	}
+}
+
+type bareKeyEntry struct {
+	Year    int `excelize-mapper:"dynamicpos:YEAR"`
+	Quarter int `excelize-mapper:"dynamicpos:QTR"`
+	Value   int `excelize-mapper:"dynamicval:"`
+}
+
+type legacyRuleModel struct {
+	BareKeys []bareKeyEntry `excelize-mapper:"dynamic:YEAR-QTR"`
+	Adjacent []DynamicEntry `excelize-mapper:"dynamic:$1Q$2"`
+}
+
+func TestLegacyDynamicRules(t *testing.T) {
+	sheetName := "Sheet1"
+	data := []legacyRuleModel{{
+		BareKeys: []bareKeyEntry{{2021, 1, 10}, {2021, 2, 20}},
+		Adjacent: []DynamicEntry{{Year: 2021, Quarter: 1, Value: floatPtr(1)}, {Year: 2021, Quarter: 2, Value: floatPtr(2)}},
+	}}
+
+	mapper := NewExcelizeMapper()
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.SetData(f, sheetName, data); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"2021-1", "2021-2", "2021Q1", "2021Q2"},
+		{"10", "20", "1", "2"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	// adjacent placeholders are written, but can't be read back
+	type adjacentModel struct {
+		Dynamic []DynamicEntry `excelize-mapper:"dynamic:$1$2"`
+	}
+	adjacent := excelize.NewFile()
+	defer adjacent.Close()
+	adjacentData := []adjacentModel{{Dynamic: []DynamicEntry{{Year: 2021, Quarter: 1, Value: floatPtr(1)}}}}
+	if err := mapper.SetData(adjacent, sheetName, adjacentData); err != nil {
+		t.Fatal(err)
+	}
+	var readBack []adjacentModel
+	err = mapper.GetData(adjacent, sheetName, &readBack)
+	if err == nil || !strings.Contains(err.Error(), "header A1: dynamic field Dynamic: adjacent placeholders") {
+		t.Errorf("err = %v, want adjacent placeholders error", err)
+	}
+
+	// rule without a dynamicpos field rolls entries up into one column
+	type rollup struct {
+		Dynamic []bareKeyEntry `excelize-mapper:"dynamic:YEAR;aggregate:sum"`
+	}
+	rollupData := []rollup{{Dynamic: []bareKeyEntry{{2021, 1, 10}, {2021, 2, 20}}}}
+	if err := mapper.SetData(f, sheetName, rollupData); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2021"}; !reflect.DeepEqual(rows[0][:1], want) || rows[1][0] != "30" {
+		t.Errorf("rows = %v, want header %v and sum 30", rows, want)
+	}
+
+	strict := NewExcelizeMapper(WithStrictTags())
+	err = strict.SetData(f, sheetName, rollupData)
+	if err == nil || !strings.Contains(err.Error(), "dynamicpos field Quarter is not used") {
+		t.Errorf("err = %v, want unused dynamicpos error", err)
+	}
+}
+
+type sortedDynamicModel struct {
+	Pos     []DynamicEntry `excelize-mapper:"dynamic:$1/$2;sort:pos"`
+	Desc    []DynamicEntry `excelize-mapper:"dynamic:$2-$1;sort:pos(-Year,Quarter)"`
+	Natural []DynamicEntry `excelize-mapper:"dynamic:Q$2;sort:natural"`
+	Custom  []DynamicEntry `excelize-mapper:"dynamic:$1.$2;sort:byQuarter"`
+}
+
+func TestSortedDynamicHeaders(t *testing.T) {
+	sheetName := "Sheet1"
+
+	row1 := []DynamicEntry{{Year: 2022, Quarter: 2}, {Year: 2021, Quarter: 10}}
+	row2 := []DynamicEntry{{Year: 2021, Quarter: 9}, {Year: 2022, Quarter: 1}}
+	originData := []sortedDynamicModel{
+		{Pos: row1, Desc: row1, Natural: row1, Custom: row1},
+		{Pos: row2, Desc: row2, Natural: row2, Custom: row2},
+	}
+
+	byQuarter := func(a, b DynamicHeader) int {
+		return compareValues(a.Key["Quarter"], b.Key["Quarter"])
+	}
+	mapper := NewExcelizeMapper(WithDynamicComparator("byQuarter", byQuarter))
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"2021/9", "2021/10", "2022/1", "2022/2",
+		"1-2022", "2-2022", "9-2021", "10-2021",
+		"Q1", "Q2", "Q9", "Q10",
+		"2022.1", "2022.2", "2021.9", "2021.10",
+	}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("headers = %v, want %v", rows[0], want)
+	}
+
+	plain := NewExcelizeMapper()
+	if err := plain.SetData(f, sheetName, originData); err == nil {
+		t.Error("expected error for unregistered comparator")
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		a, b interface{}
+		want int
+	}{
+		{1, 2, -1},
+		{uint8(3), uint8(2), 1},
+		{1.5, 1, 1},
+		{now, now.Add(time.Hour), -1},
+		{"Q2", "Q10", -1},
+		{"a01", "a1", 1},
+		{nil, 0, -1},
+		{true, false, 1},
+	}
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDynamicDomainSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []DynamicModel{
+		{Text: "text1", Dynamic: []DynamicEntry{
+			{Year: 2021, Quarter: 2, Value: floatPtr(2)},
+			{Year: 2020, Quarter: 4, Value: floatPtr(4)},
+		}},
+	}
+
+	var domain []DynamicKey
+	for quarter := 1; quarter <= 4; quarter++ {
+		domain = append(domain, DynamicKey{"Year": 2021, "Quarter": quarter})
+	}
+
+	mapper := NewExcelizeMapper(
+		WithDynamicDomain("Dynamic", domain...),
+		WithDynamicFill("Dynamic", 0),
+	)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"CargoCode", "Text", "2021/1", "2021/2", "2021/3", "2021/4"},
+		{"", "text1", "0", "2", "0", "0"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+type aggregateEntry struct {
+	Month string      `excelize-mapper:"dynamicpos:"`
+	Value interface{} `excelize-mapper:"dynamicval:"`
+}
+
+type aggregateModel struct {
+	First []aggregateEntry `excelize-mapper:"dynamic:first {Month};aggregate:first"`
+	Last  []aggregateEntry `excelize-mapper:"dynamic:last {Month}"`
+	Sum   []aggregateEntry `excelize-mapper:"dynamic:sum {Month};aggregate:sum"`
+	Min   []aggregateEntry `excelize-mapper:"dynamic:min {Month};aggregate:min"`
+	Max   []aggregateEntry `excelize-mapper:"dynamic:max {Month};aggregate:max"`
+	Join  []aggregateEntry `excelize-mapper:"dynamic:join {Month};aggregate:join(|)"`
+}
+
+type aggregateErrorModel struct {
+	Text  string           `excelize-mapper:"header:Text"`
+	Error []aggregateEntry `excelize-mapper:"dynamic:{Month};aggregate:error"`
+}
+
+func TestDynamicAggregateSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	entries := []aggregateEntry{{"Jan", 3}, {"Jan", 1.5}, {"Jan", nil}, {"Jan", 2}}
+	originData := []aggregateModel{{
+		First: entries, Last: entries, Sum: entries, Min: entries, Max: entries, Join: entries,
+	}}
+
+	mapper := NewExcelizeMapper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"3", "2", "6.5", "1.5", "3", "3|1.5|2"}
+	if !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("values = %v, want %v", rows[1], want)
+	}
+
+	errorData := []aggregateErrorModel{
+		{Text: "ok", Error: []aggregateEntry{{"Jan", 1}, {"Feb", 2}}},
+		{Text: "dup", Error: []aggregateEntry{{"Jan", 1}, {"Jan", 2}}},
+	}
+	f2 := excelize.NewFile()
+	defer f2.Close()
+	err = mapper.SetData(f2, sheetName, errorData)
+	if err == nil || !strings.Contains(err.Error(), `row 3`) || !strings.Contains(err.Error(), `"Jan"`) {
+		t.Errorf("err = %v, want duplicate header error for row 3", err)
+	}
+}
+
+type formattedDynamicEntry struct {
+	Month string  `excelize-mapper:"dynamicpos:"`
+	Value float64 `excelize-mapper:"dynamicval:;default:none;numfmt:0.00"`
+}
+
+type formattedDynamicModel struct {
+	Months []formattedDynamicEntry `excelize-mapper:"dynamic:{Month};width:30;style:bold;numfmt:0"`
+	Labels []aggregateEntry        `excelize-mapper:"dynamic:{Month};format:label"`
+}
+
+func TestFormattedDynamicSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []formattedDynamicModel{{
+		Months: []formattedDynamicEntry{{"Jan", 1.5}, {"Feb", 0}},
+		Labels: []aggregateEntry{{"Mar", 3}},
+	}}
+
+	label := func(v interface{}) string {
+		return fmt.Sprintf("<%v>", v)
+	}
+	mapper := NewExcelizeMapper(
+		WithFormatter("label", label),
+		WithStyle("bold", &excelize.Style{Font: &excelize.Font{Bold: true}}),
+	)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"Jan", "Feb", "Mar"},
+		{"1.50", "none", "<3>"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	width, err := f.GetColWidth(sheetName, "B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 30 {
+		t.Errorf("width = %v, want 30", width)
+	}
+
+	styleID, err := f.GetCellStyle(sheetName, "A2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style.Font == nil || !style.Font.Bold {
+		t.Errorf("style font = %+v, want bold", style.Font)
+	}
+}
+
+type positionedDynamicModel struct {
+	Product string           `excelize-mapper:"header:Product"`
+	Total   float64          `excelize-mapper:"header:Total"`
+	Note    string           `excelize-mapper:"header:Note"`
+	Months  []aggregateEntry `excelize-mapper:"dynamic:{Month};after:Product"`
+	Flags   []aggregateEntry `excelize-mapper:"dynamic:flag {Month};index:2"`
+}
+
+func TestPositionedDynamicSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []positionedDynamicModel{{
+		Product: "apple",
+		Total:   3,
+		Note:    "note",
+		Months:  []aggregateEntry{{"Jan", 1}, {"Feb", 2}},
+		Flags:   []aggregateEntry{{"Jan", "x"}},
+	}}
+
+	mapper := NewExcelizeMapper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"Product", "Jan", "Feb", "Total", "flag Jan", "Note"},
+		{"apple", "1", "2", "3", "x", "note"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+type pointerDynamicModel struct {
+	Text    string          `excelize-mapper:"header:Text"`
+	Dynamic []*DynamicEntry `excelize-mapper:"dynamic:$1/$2"`
+}
+
+func TestPointerDynamicSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []interface{}{
+		&pointerDynamicModel{Text: "text1", Dynamic: []*DynamicEntry{
+			{Year: 2021, Quarter: 1, Value: floatPtr(1)},
+			nil,
+		}},
+		nil,
+		pointerDynamicModel{Text: "text3", Dynamic: []*DynamicEntry{
+			nil,
+			{Year: 2021, Quarter: 2, Value: floatPtr(2)},
+		}},
+	}
+
+	mapper := NewExcelizeMapper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"Text", "2021/1", "2021/2"},
+		{"text1", "1"},
+		nil,
+		{"text3", "", "2"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %#v, want %#v", rows, want)
+	}
+
+	mixedData := []interface{}{pointerDynamicModel{}, baseData}
+	if err := mapper.SetData(f, sheetName, mixedData); err == nil {
+		t.Error("expected error for mixed row types")
+	}
+
+	if err := mapper.SetData(f, sheetName, []interface{}{nil}); err == nil {
+		t.Error("expected error for unresolved row type")
+	}
+
+	if err := mapper.SetData(f, sheetName, []int{1}); err == nil {
+		t.Error("expected error for non-struct rows")
+	}
+
+}
+
+type interfaceDynamicModel struct {
+	Text    string        `excelize-mapper:"header:Text"`
+	Dynamic []interface{} `excelize-mapper:"dynamic:$1/$2"`
+}
+
+func TestInterfaceDynamicEntries(t *testing.T) {
+	sheetName := "Sheet1"
+	mapper := NewExcelizeMapper()
+
+	originData := []interfaceDynamicModel{
+		{Text: "text1", Dynamic: []interface{}{nil, DynamicEntry{Year: 2021, Quarter: 1, Value: floatPtr(1)}}},
+		{Text: "text2", Dynamic: []interface{}{&DynamicEntry{Year: 2021, Quarter: 2, Value: floatPtr(2)}}},
+		{Text: "text3"},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.SetData(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Text", "2021/1", "2021/2"},
+		{"text1", "1"},
+		{"text2", "", "2"},
+		{"text3"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %#v, want %#v", rows, want)
+	}
+
+	// type of entries is unknown when sheet is read, dynamic columns are skipped
+	var data []interfaceDynamicModel
+	if err := mapper.GetData(f, sheetName, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 || data[0].Text != "text1" || data[0].Dynamic != nil {
+		t.Errorf("data = %+v", data)
+	}
+
+	if err := mapper.SetData(f, sheetName, []interfaceDynamicModel{{Text: "empty"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	mixed := []interfaceDynamicModel{{Dynamic: []interface{}{DynamicEntry{}, bareKeyEntry{}}}}
+	err = mapper.SetData(f, sheetName, mixed)
+	if err == nil || !strings.Contains(err.Error(), "entry 1 is excelizemapper.bareKeyEntry, want excelizemapper.DynamicEntry") {
+		t.Errorf("err = %v, want mixed entry types error", err)
+	}
+
+	notStruct := []interfaceDynamicModel{{Dynamic: []interface{}{1}}}
+	if err := mapper.SetData(f, sheetName, notStruct); err == nil || !strings.Contains(err.Error(), "entry int is not struct") {
+		t.Errorf("err = %v, want not struct error", err)
+	}
+}
+
+type groupedDynamicModel struct {
+	Text    string         `excelize-mapper:"header:Text"`
+	Dynamic []DynamicEntry `excelize-mapper:"dynamic:{Year}>Q{Quarter}"`
+}
+
+func TestGroupedDynamicSetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []groupedDynamicModel{
+		{Text: "text1", Dynamic: []DynamicEntry{
+			{Year: 2021, Quarter: 1, Value: floatPtr(1)},
+			{Year: 2022, Quarter: 1, Value: floatPtr(3)},
+			{Year: 2021, Quarter: 2, Value: floatPtr(2)},
+		}},
+	}
+
+	mapper := NewExcelizeMapper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	err := mapper.SetData(f, sheetName, originData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"Text", "2021", "", "2022"},
+		{"", "Q1", "Q2", "Q1"},
+		{"text1", "1", "2", "3"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	mergeCells, err := f.GetMergeCells(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged []string
+	for _, mc := range mergeCells {
+		merged = append(merged, mc.GetStartAxis()+":"+mc.GetEndAxis())
+	}
+	slices.Sort(merged)
+	wantMerged := []string{"A1:A2", "B1:C1"}
+	if !reflect.DeepEqual(merged, wantMerged) {
+		t.Errorf("merged = %v, want %v", merged, wantMerged)
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	mapper := NewExcelizeMapper()
+
+	cols1, _, err := mapper.parse([]DynamicModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols2, _, err := mapper.parse([]*DynamicModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &cols1[0] != &cols2[0] {
+		t.Error("expected schema to be cached")
+	}
+
+	manual := NewExcelizeMapper(WithAutoSort(false))
+	cols3, _, err := manual.parse([]DynamicModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols3) != 0 {
+		t.Errorf("columns = %v, want none without index tags", cols3)
+	}
+
+	originData := []DynamicModel{{Text: "text1", Dynamic: []DynamicEntry{{Year: 2021, Quarter: 1}}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			f := excelize.NewFile()
+			defer f.Close()
+			if err := mapper.SetData(f, "Sheet1", originData); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkSetData(b *testing.B) {
+	originData := []customSortModel{customSortData, customSortData}
+	mapper := NewExcelizeMapper(WithAutoSort(false))
+
+	for i := 0; i < b.N; i++ {
+		f := excelize.NewFile()
+		if err := mapper.SetData(f, "Sheet1", originData); err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+	}
+}
+
+type schemaModel struct {
+	Name  string         `excelize-mapper:"header:Name;width:20;hint:user name"`
+	Value float64        `excelize-mapper:"header:Value;format:money;numfmt:0.00"`
+	Dyn   []DynamicEntry `excelize-mapper:"dynamic:$1/$2;sort:pos;width:12"`
+}
+
+func TestSchema(t *testing.T) {
+	mapper := NewExcelizeMapper()
+
+	for _, sample := range []interface{}{schemaModel{}, &schemaModel{}, []*schemaModel{}} {
+		columns, rules, err := mapper.Schema(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(columns) != 2 || len(rules) != 1 {
+			t.Fatalf("got %d columns and %d rules, want 2 and 1", len(columns), len(rules))
+		}
+		if columns[0].ColumnWidth != 20 || columns[0].Tags["hint"] != "user name" {
+			t.Errorf("column = %+v, want width 20 and hint tag", columns[0])
+		}
+		if columns[1].FormatterKey != "money" || columns[1].NumFmt != "0.00" {
+			t.Errorf("column = %+v, want money formatter and number format", columns[1])
+		}
+		if rules[0].ParentFieldName != "Dyn" || rules[0].SortRule != "pos" || rules[0].Value.ColumnWidth != 12 {
+			t.Errorf("rules = %+v", rules[0])
+		}
+
+		// returned schema is a copy
+		columns[0].Tags["hint"] = "changed"
+		rules[0].Mappings["$1"] = "changed"
+	}
+
+	if _, _, err := mapper.Schema(1); err == nil {
+		t.Error("expected error for non-struct sample")
+	}
+
+	columns, _, err := NewMapper[schemaModel]().Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns[0].HeaderName != "Name" {
+		t.Errorf("header = %q, want Name", columns[0].HeaderName)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	p := NewExcelizeMapper().parser
+
+	cases := []struct {
+		tag     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"header:Name;width:20", map[string]string{"header": "Name", "width": "20"}, false},
+		{"header: Name ; omitempty", map[string]string{"header": "Name", "omitempty": ""}, false},
+		{"header:'Ratio: A;B';width:20", map[string]string{"header": "Ratio: A;B", "width": "20"}, false},
+		{`header:' it\'s \\ '`, map[string]string{"header": ` it's \ `}, false},
+		{`header:A\;B;format:time(15:04)`, map[string]string{"header": "A;B", "format": "time(15:04)"}, false},
+		{"header:Don't", map[string]string{"header": "Don't"}, false},
+		{"header:'A", map[string]string{"header": "A"}, true},
+		{"header:'A'B", map[string]string{"header": "A"}, true},
+		{":A;width:1", map[string]string{"width": "1"}, true},
+		{"header:A;header:B", map[string]string{"header": "B"}, true},
+	}
+
+	for _, c := range cases {
+		got, err := p.parseTags(c.tag)
+		if (err != nil) != c.wantErr {
+			t.Errorf("parseTags(%q) error = %v, wantErr %v", c.tag, err, c.wantErr)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parseTags(%q) = %q, want %q", c.tag, got, c.want)
+		}
+	}
+}
+
+func TestStrictTags(t *testing.T) {
+	type unknownKey struct {
+		Name string `excelize-mapper:"header:Name;widht:20"`
+	}
+	type badWidth struct {
+		Name string `excelize-mapper:"header:Name;width:wide"`
+	}
+	type badFormatter struct {
+		Name string `excelize-mapper:"header:Name;format:missing"`
+	}
+	type badPlaceholder struct {
+		Dynamic []DynamicEntry `excelize-mapper:"dynamic:{$1|missing}/{$2}"`
+	}
+	type badIndex struct {
+		Name string `excelize-mapper:"header:Name;index:abc"`
+	}
+	type negativeAutoIndex struct {
+		Name string `excelize-mapper:"header:Name;index:-1"`
+	}
+	type valid struct {
+		Label string  `excelize-mapper:"header:A\\;B"`
+		Name  string  `excelize-mapper:"header:'Name: full';width:20;omitempty"`
+		Price float64 `excelize-mapper:"header:Price;format:fixed(2)"`
+	}
+
+	cases := []struct {
+		data    any
+		wantErr string
+	}{
+		{[]unknownKey{}, `field Name: unknown key "widht"`},
+		{[]badWidth{}, `field Name: key "width": invalid width "wide"`},
+		{[]badFormatter{}, `field Name: key "format": formatter "missing" not registered`},
+		{[]badPlaceholder{}, `field Dynamic: key "dynamic": formatter "missing" not registered`},
+		{[]badIndex{}, `field Name: key "index": invalid index "abc"`},
+		{[]negativeAutoIndex{}, `field Name: key "index": invalid index "-1"`},
+		{[]valid{}, ""},
+	}
+
+	strict := NewExcelizeMapper(WithStrictTags())
+	lenient := NewExcelizeMapper()
+	for _, c := range cases {
+		f := excelize.NewFile()
+		err := strict.SetData(f, "Sheet1", c.data)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%T: unexpected error %v", c.data, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("%T: error = %v, want %q", c.data, err, c.wantErr)
+		}
+
+		if err := lenient.SetData(f, "Sheet1", c.data); err != nil {
+			t.Errorf("%T: lenient error %v", c.data, err)
+		}
+		f.Close()
+	}
+
+	columns, _, err := strict.Schema(valid{})
+	if err != nil || columns[0].HeaderName != "A;B" {
+		t.Errorf("columns = %+v, err = %v, want header %q", columns, err, "A;B")
+	}
+
+	// "\;" written with single backslash is not a valid struct tag
+	field := reflect.StructField{Name: "Name", Tag: reflect.StructTag(`excelize-mapper:"header:X\;Y"`)}
+	if _, err := strict.parser.getTagsByKey(field); err == nil || !strings.Contains(err.Error(), "bad syntax") {
+		t.Errorf("err = %v, want bad syntax error", err)
+	}
+	if tags, err := lenient.parser.getTagsByKey(field); err != nil || tags != nil {
+		t.Errorf("lenient tags = %v, err = %v, want untagged field", tags, err)
+	}
+}
+
+type embeddedIDs struct {
+	ID   int    `excelize-mapper:"header:ID"`
+	Code string `excelize-mapper:"header:Code"`
+}
+
+type embeddingModel struct {
+	Name string `excelize-mapper:"header:Name"`
+	embeddedIDs
+	Price float64 `excelize-mapper:"header:Price"`
+}
+
+func TestValidateSchema(t *testing.T) {
+	mapper := NewExcelizeMapper()
+	columns, _, err := mapper.Schema(embeddingModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var headers []string
+	for _, column := range columns {
+		headers = append(headers, fmt.Sprintf("%d:%s", column.ColumnIndex, column.HeaderName))
+	}
+	if want := []string{"0:Name", "1:ID", "2:Code", "3:Price"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("headers = %v, want %v", headers, want)
+	}
+
+	type duplicateIndex struct {
+		A string `excelize-mapper:"header:A;index:3"`
+		B string `excelize-mapper:"header:B;index:3"`
+	}
+	type negativeIndex struct {
+		A string `excelize-mapper:"header:A;index:-1"`
+	}
+	type duplicateHeader struct {
+		A string `excelize-mapper:"header:Name"`
+		B string `excelize-mapper:"header:Name"`
+	}
+	type viewHeaders struct {
+		A string `excelize-mapper:"header:Price;index:0;views:admin"`
+		B string `excelize-mapper:"header:Price;index:0;views:customer"`
+	}
+
+	cases := []struct {
+		data    any
+		opts    []Option
+		wantErr string
+	}{
+		{[]duplicateIndex{}, []Option{WithAutoSort(false)}, "fields A and B: duplicate index 3"},
+		{[]negativeIndex{}, []Option{WithAutoSort(false)}, "field A: negative index -1"},
+		{[]duplicateHeader{}, nil, `fields A and B: duplicate header "Name"`},
+		{[]viewHeaders{}, []Option{WithAutoSort(false)}, ""},
+	}
+
+	for _, c := range cases {
+		mapper := NewExcelizeMapper(c.opts...)
+		f := excelize.NewFile()
+		err := mapper.SetData(f, "Sheet1", c.data)
+		f.Close()
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%T: unexpected error %v", c.data, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("%T: error = %v, want %q", c.data, err, c.wantErr)
+		}
+	}
+
+	dup := NewSchema[thirdPartyModel]().
+		Column("Name", func(m thirdPartyModel) any { return m.Name }).
+		Column("Price", func(m thirdPartyModel) any { return m.Price }, Index(0))
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := NewMapper[thirdPartyModel](WithSchema(dup)).Write(f, "Sheet1", nil); err == nil {
+		t.Error("expected duplicate index error of code-first schema")
+	}
+}
+
+type units int
+
+type unitsEntry struct {
+	Month string `excelize-mapper:"dynamicpos:"`
+	Value units  `excelize-mapper:"dynamicval:"`
+}
+
+type unitsModel struct {
+	Formatted []unitsEntry `excelize-mapper:"dynamic:{Month};aggregate:sum;format:units"`
+	Typed     []unitsEntry `excelize-mapper:"dynamic:typed {Month};aggregate:sum"`
+}
+
+func TestDynamicSumKeepsType(t *testing.T) {
+	sheetName := "Sheet1"
+	entries := []unitsEntry{{"Jan", 1}, {"Jan", 2}, {"Feb", 4}}
+
+	mapper := NewExcelizeMapper(
+		WithFormatter("units", func(value interface{}) string {
+			return fmt.Sprintf("%d pcs", value.(units))
+		}),
+		WithTypeFormatter(reflect.TypeOf(units(0)), func(value interface{}) string {
+			return fmt.Sprintf("%d u", value.(units))
+		}),
+	)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.SetData(f, sheetName, []unitsModel{{Formatted: entries, Typed: entries}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"3 pcs", "4 pcs", "3 u", "4 u"}
+	if !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("values = %v, want %v", rows[1], want)
+	}
+}
@@ -32,3 +32,11 @@ func SliceFormatter(val interface{}) string {
 func (em *ExcelizeMapper) SetFormatter(name string, format Format) {
 	em.options.formatterMap[name] = format
 }
+
+func (em *ExcelizeMapper) SetParser(name string, parse Parse) {
+	em.options.parserMap[name] = parse
+}
+
+func (em *ExcelizeMapper) SetContextFormatter(name string, format ContextFormat) {
+	em.options.contextMap[name] = format
+}
@@ -0,0 +1,56 @@
+package excelizemapper
+
+import "slices"
+
+// HeaderTranslator returns header of message key in language lang,
+// empty result means key has no translation.
+type HeaderTranslator func(key, lang string) string
+
+// WithHeaderTranslator set translator of static column headers,
+// header tag values are used as message keys.
+func WithHeaderTranslator(translator HeaderTranslator) Option {
+	return func(o *options) {
+		o.translator = translator
+	}
+}
+
+// WithLanguage set language of written headers, use ExcelizeMapper.With for per-call language
+func WithLanguage(lang string) Option {
+	return func(o *options) {
+		o.language = lang
+	}
+}
+
+// WithLanguages set supported languages, GetData accepts headers in any of them.
+func WithLanguages(langs ...string) Option {
+	return func(o *options) {
+		o.languages = append(o.languages, langs...)
+	}
+}
+
+// translate returns header of column in language lang
+func (em *ExcelizeMapper) translate(column Column, lang string) string {
+	if em.options.translator == nil || lang == "" {
+		return column.HeaderName
+	}
+	if header := em.options.translator(column.HeaderName, lang); header != "" {
+		return header
+	}
+	return column.HeaderName
+}
+
+// headerName returns written header of column
+func (em *ExcelizeMapper) headerName(column Column) string {
+	return em.translate(column, em.options.language)
+}
+
+// acceptsHeader reports whether header read from sheet belongs to column,
+// it is message key or translation in any supported language.
+func (em *ExcelizeMapper) acceptsHeader(column Column, header string) bool {
+	if header == column.HeaderName || header == em.headerName(column) {
+		return true
+	}
+	return slices.ContainsFunc(em.options.languages, func(lang string) bool {
+		return header == em.translate(column, lang)
+	})
+}
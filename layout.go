@@ -0,0 +1,165 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// columnLayout holds final sheet positions of static columns and dynamic blocks
+type columnLayout struct {
+	width   int
+	static  []int
+	dynamic []int
+}
+
+// newColumnLayout places dynamic blocks at their index or after their anchor
+// field shifting following static columns right, blocks without position go last.
+func newColumnLayout(columns []Column, dynamicRules []*DynamicRules, dynamicSizes []int) (columnLayout, error) {
+	layout := columnLayout{
+		static:  make([]int, len(columns)),
+		dynamic: make([]int, len(dynamicRules)),
+	}
+
+	staticWidth := 0
+	for _, column := range columns {
+		if column.ColumnIndex+1 > staticWidth {
+			staticWidth = column.ColumnIndex + 1
+		}
+	}
+
+	type block struct {
+		rule   int
+		anchor int
+	}
+	var positioned, trailing []block
+	for i, rules := range dynamicRules {
+		anchor, err := dynamicAnchor(rules, columns)
+		if err != nil {
+			return columnLayout{}, err
+		}
+		if anchor < 0 || anchor > staticWidth {
+			trailing = append(trailing, block{rule: i, anchor: staticWidth})
+			continue
+		}
+		positioned = append(positioned, block{rule: i, anchor: anchor})
+	}
+	sort.SliceStable(positioned, func(i, j int) bool {
+		return positioned[i].anchor < positioned[j].anchor
+	})
+
+	// shift returns count of dynamic columns placed before static index
+	shift := func(index int) int {
+		n := 0
+		for _, b := range positioned {
+			if b.anchor <= index {
+				n += dynamicSizes[b.rule]
+			}
+		}
+		return n
+	}
+
+	for i, column := range columns {
+		layout.static[i] = column.ColumnIndex + shift(column.ColumnIndex)
+	}
+
+	offset := 0
+	for _, b := range positioned {
+		layout.dynamic[b.rule] = b.anchor + offset
+		offset += dynamicSizes[b.rule]
+	}
+
+	layout.width = staticWidth + offset
+	for _, b := range trailing {
+		layout.dynamic[b.rule] = layout.width
+		layout.width += dynamicSizes[b.rule]
+	}
+
+	return layout, nil
+}
+
+// dynamicAnchor returns static index dynamic block is placed at, -1 for last
+func dynamicAnchor(rules *DynamicRules, columns []Column) (int, error) {
+	if rules.After == "" {
+		return rules.Index, nil
+	}
+
+	for _, column := range columns {
+		if matchFieldName(column.FieldName, rules.After) {
+			return column.ColumnIndex + 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("dynamic field %s: field %q to place after not found", rules.ParentFieldName, rules.After)
+}
+
+// setHeaders writes header rows, with several header rows static headers and
+// dynamic leaf headers are merged down to the last header row and equal
+// adjacent group labels are merged across.
+func (em *ExcelizeMapper) setHeaders(f *excelize.File, sheet string, headerRows int, layout columnLayout,
+	columns []Column, dynamicRules []*DynamicRules, dynamicHeaders [][]DynamicHeader,
+) error {
+	grid := make([][]string, headerRows)
+	for i := range grid {
+		grid[i] = make([]string, layout.width)
+	}
+	var merges [][4]int
+
+	for i, column := range columns {
+		grid[0][layout.static[i]] = em.headerName(column)
+		merges = append(merges, [4]int{layout.static[i], 0, layout.static[i], headerRows - 1})
+	}
+
+	for i, rules := range dynamicRules {
+		start := layout.dynamic[i]
+		leafRow := rules.Levels() - 1
+		for j, header := range dynamicHeaders[i] {
+			grid[leafRow][start+j] = header.Header
+			merges = append(merges, [4]int{start + j, leafRow, start + j, headerRows - 1})
+		}
+
+		for level := 0; level < leafRow; level++ {
+			for j := 0; j < len(dynamicHeaders[i]); {
+				k := j + 1
+				for k < len(dynamicHeaders[i]) &&
+					slices.Equal(dynamicHeaders[i][j].Groups[:level+1], dynamicHeaders[i][k].Groups[:level+1]) {
+					k++
+				}
+				grid[level][start+j] = dynamicHeaders[i][j].Groups[level]
+				merges = append(merges, [4]int{start + j, level, start + k - 1, level})
+				j = k
+			}
+		}
+	}
+
+	for row := range grid {
+		cell, err := excelize.CoordinatesToCellName(1, row+1)
+		if err != nil {
+			return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+		}
+		if err := f.SetSheetRow(sheet, cell, &grid[row]); err != nil {
+			return fmt.Errorf("excelize SetSheetRow error: %w", err)
+		}
+	}
+
+	for _, m := range merges {
+		if m[0] == m[2] && m[1] == m[3] {
+			continue
+		}
+		topLeft, err := excelize.CoordinatesToCellName(m[0]+1, m[1]+1)
+		if err != nil {
+			return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+		}
+		bottomRight, err := excelize.CoordinatesToCellName(m[2]+1, m[3]+1)
+		if err != nil {
+			return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+		}
+		if err := f.MergeCell(sheet, topLeft, bottomRight); err != nil {
+			return fmt.Errorf("excelize MergeCell error: %w", err)
+		}
+	}
+
+	return nil
+}
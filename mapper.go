@@ -0,0 +1,89 @@
+package excelizemapper
+
+import (
+	"reflect"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Mapper is a typed ExcelizeMapper for rows of type T, schema of T is parsed once.
+//
+// T is a struct or a pointer to struct.
+type Mapper[T any] struct {
+	em      ExcelizeMapper
+	columns []Column
+	rules   []*DynamicRules
+	err     error
+}
+
+// NewMapper creates typed mapper, schema error is returned by Write and Read.
+func NewMapper[T any](opts ...Option) *Mapper[T] {
+	m := &Mapper[T]{em: NewExcelizeMapper(opts...)}
+
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+	if itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	m.columns, m.rules, m.err = m.em.schemaOf(itemType)
+
+	return m
+}
+
+// Write writes data into sheet
+func (m *Mapper[T]) Write(f *excelize.File, sheet string, data []T) error {
+	if m.err != nil {
+		return m.err
+	}
+	return m.em.setData(f, sheet, m.columns, m.rules, reflect.ValueOf(data))
+}
+
+// Read reads rows of sheet
+func (m *Mapper[T]) Read(f *excelize.File, sheet string) ([]T, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var data []T
+	if err := m.em.getData(f, sheet, m.columns, m.rules, reflect.ValueOf(&data).Elem()); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// TypedFormatter converts typed formatter to Format, values of other types
+// (e.g. nil pointers) are formatted to empty string.
+func TypedFormatter[V any](format func(V) string) Format {
+	return func(val interface{}) string {
+		if v, ok := val.(V); ok {
+			return format(v)
+		}
+		return ""
+	}
+}
+
+// WithTypedFormatter set typed formatter
+func WithTypedFormatter[V any](name string, format func(V) string) Option {
+	return WithFormatter(name, TypedFormatter(format))
+}
+
+// TypedParser converts typed parser to Parse
+func TypedParser[V any](parse func(string) (V, error)) Parse {
+	return func(text string) (interface{}, error) {
+		return parse(text)
+	}
+}
+
+// WithTypedParser set typed parser
+func WithTypedParser[V any](name string, parse func(string) (V, error)) Option {
+	return WithParser(name, TypedParser(parse))
+}
+
+// WithFormatterFor set formatter of all columns of type V, see WithTypeFormatter
+func WithFormatterFor[V any](format func(V) string) Option {
+	return WithTypeFormatter(reflect.TypeOf((*V)(nil)).Elem(), TypedFormatter(format))
+}
+
+// WithParserFor set parser of all columns of type V, see WithTypeParser
+func WithParserFor[V any](parse func(string) (V, error)) Option {
+	return WithTypeParser(reflect.TypeOf((*V)(nil)).Elem(), TypedParser(parse))
+}
@@ -0,0 +1,960 @@
+package excelizemapper
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type mapperModel struct {
+	ID       int             `excelize-mapper:"header:ID"`
+	Name     string          `excelize-mapper:"header:Name"`
+	Desc     *string         `excelize-mapper:"header:Desc"`
+	Sex      Sex             `excelize-mapper:"header:Sex;format:sex"`
+	Score    float64         `excelize-mapper:"header:Score"`
+	Enabled  bool            `excelize-mapper:"header:Enabled"`
+	Created  time.Time       `excelize-mapper:"header:Created"`
+	Quarters []*DynamicEntry `excelize-mapper:"dynamic:{Year}>Q{Quarter};sort:pos"`
+}
+
+type Sex int
+
+func TestMapperRoundTrip(t *testing.T) {
+	sheetName := "Sheet1"
+
+	desc := "desc"
+	created := time.Date(2023, 12, 21, 15, 38, 29, 0, time.UTC)
+	originData := []mapperModel{{
+		ID:      1,
+		Name:    "Tom",
+		Desc:    &desc,
+		Sex:     1,
+		Score:   1.5,
+		Enabled: true,
+		Created: created,
+		Quarters: []*DynamicEntry{
+			{Year: 2021, Quarter: 2, Value: floatPtr(2)},
+			{Year: 2021, Quarter: 1, Value: floatPtr(1)},
+			{Year: 2022, Quarter: 1, Value: floatPtr(3)},
+		},
+	}, {
+		ID:      2,
+		Name:    "Jerry",
+		Created: created,
+		Quarters: []*DynamicEntry{
+			{Year: 2022, Quarter: 1, Value: floatPtr(4)},
+		},
+	}}
+
+	mapper := NewMapper[mapperModel](
+		WithTypedFormatter("sex", func(s Sex) string {
+			return []string{"Male", "Female"}[s]
+		}),
+		WithTypedParser("sex", func(text string) (Sex, error) {
+			switch text {
+			case "Male":
+				return 0, nil
+			case "Female":
+				return 1, nil
+			}
+			return 0, fmt.Errorf("unknown sex %q", text)
+		}),
+	)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rows[2][3]; got != "Female" {
+		t.Errorf("sex cell = %q, want Female", got)
+	}
+
+	data, err := mapper.Read(f, sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantData := []mapperModel{originData[0], originData[1]}
+	wantData[0].Quarters = []*DynamicEntry{originData[0].Quarters[1], originData[0].Quarters[0], originData[0].Quarters[2]}
+	if !reflect.DeepEqual(data, wantData) {
+		t.Errorf("data = %+v, want %+v", data, wantData)
+	}
+
+	if err := f.SetCellValue(sheetName, "D3", "Unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mapper.Read(f, sheetName); err == nil || !strings.Contains(err.Error(), "cell D3") {
+		t.Errorf("err = %v, want error for cell D3", err)
+	}
+}
+
+func TestGetData(t *testing.T) {
+	sheetName := "Sheet1"
+
+	originData := []*DynamicModel{
+		{Text: "text1", CargoCode: intPtr(1), Dynamic: []DynamicEntry{{Year: 2021, Quarter: 1, Value: floatPtr(1)}}},
+	}
+
+	mapper := NewExcelizeMapper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.SetData(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	var data []*DynamicModel
+	if err := mapper.GetData(f, sheetName, &data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, originData) {
+		t.Errorf("data = %+v, want %+v", data, originData)
+	}
+
+	if err := mapper.GetData(f, sheetName, data); err == nil {
+		t.Error("expected error for non-pointer data")
+	}
+}
+
+func TestMapperInvalidType(t *testing.T) {
+	mapper := NewMapper[int]()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, "Sheet1", []int{1}); err == nil {
+		t.Error("expected error for non-struct type")
+	}
+}
+
+// thirdPartyModel has no tags, like types of generated packages
+type thirdPartyModel struct {
+	Name  string
+	Price float64
+	Tags  []string
+}
+
+func TestSchemaBuilder(t *testing.T) {
+	sheetName := "Sheet1"
+
+	schema := NewSchema[*thirdPartyModel]().
+		Column("Name", func(m *thirdPartyModel) any { return m.Name },
+			Width(20), Setter(func(m *thirdPartyModel, name string) { m.Name = name })).
+		Column("Tags", func(m *thirdPartyModel) any { return m.Tags },
+			Formatter("slice"), Index(3)).
+		Column("Price", func(m *thirdPartyModel) any { return m.Price },
+			NumFmt("0.00"), Setter(func(m *thirdPartyModel, price float64) { m.Price = price }))
+
+	mapper := NewExcelizeMapper(
+		WithSchema(schema),
+		WithFormatter("slice", SliceFormatter),
+	)
+
+	originData := []thirdPartyModel{{Name: "apple", Price: 1.5, Tags: []string{"a", "b"}}}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.SetData(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Name", "", "Price", "Tags"},
+		{"apple", "", "1.50", "a, b"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	var data []thirdPartyModel
+	if err := mapper.GetData(f, sheetName, &data); err != nil {
+		t.Fatal(err)
+	}
+	wantData := []thirdPartyModel{{Name: "apple", Price: 1.5}}
+	if !reflect.DeepEqual(data, wantData) {
+		t.Errorf("data = %+v, want %+v", data, wantData)
+	}
+
+	columns, _, err := mapper.Schema(thirdPartyModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 3 || columns[0].ColumnWidth != 20 {
+		t.Errorf("columns = %+v", columns)
+	}
+
+	badSchema := NewSchema[thirdPartyModel]().
+		Column("Name", func(m thirdPartyModel) any { return m.Name },
+			Setter(func(m *mapperModel, name string) { m.Name = name }))
+	bad := NewMapper[thirdPartyModel](WithSchema(badSchema))
+	if err := bad.Write(f, sheetName, originData); err == nil {
+		t.Error("expected error for setter of other type")
+	}
+}
+
+type viewModel struct {
+	Name   string           `excelize-mapper:"header:Name"`
+	Cost   float64          `excelize-mapper:"header:Cost;views:admin,finance"`
+	Price  float64          `excelize-mapper:"header:Price"`
+	Months []aggregateEntry `excelize-mapper:"dynamic:{Month};views:admin"`
+	Note   string           `excelize-mapper:"header:Note"`
+}
+
+func TestViews(t *testing.T) {
+	sheetName := "Sheet1"
+	mapper := NewExcelizeMapper()
+
+	originData := []viewModel{
+		{Name: "apple", Cost: 1, Price: 2, Months: []aggregateEntry{{Month: "Jan", Value: 3}}, Note: "fresh"},
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+		want [][]string
+	}{
+		{"all", nil, [][]string{
+			{"Name", "Cost", "Price", "Note", "Jan"},
+			{"apple", "1", "2", "fresh", "3"},
+		}},
+		{"admin", []Option{WithView("admin")}, [][]string{
+			{"Name", "Cost", "Price", "Note", "Jan"},
+			{"apple", "1", "2", "fresh", "3"},
+		}},
+		{"customer", []Option{WithView("customer")}, [][]string{
+			{"Name", "Price", "Note"},
+			{"apple", "2", "fresh"},
+		}},
+		{"columns", []Option{WithColumns("Price", "Name")}, [][]string{
+			{"Price", "Name"},
+			{"2", "apple"},
+		}},
+		{"without", []Option{WithoutColumns("Cost", "Months")}, [][]string{
+			{"Name", "Price", "Note"},
+			{"apple", "2", "fresh"},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			view := mapper.With(c.opts...)
+
+			f := excelize.NewFile()
+			defer f.Close()
+			if err := view.SetData(f, sheetName, originData); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := f.GetRows(sheetName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows, c.want) {
+				t.Errorf("rows = %v, want %v", rows, c.want)
+			}
+
+			var data []viewModel
+			if err := view.GetData(f, sheetName, &data); err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != 1 || data[0].Name != "apple" || data[0].Price != 2 {
+				t.Errorf("data = %+v", data)
+			}
+		})
+	}
+}
+
+type indexedViewModel struct {
+	A      string           `excelize-mapper:"header:A"`
+	B      string           `excelize-mapper:"header:B"`
+	C      string           `excelize-mapper:"header:C"`
+	Months []aggregateEntry `excelize-mapper:"dynamic:{Month};index:1"`
+}
+
+func TestViewsKeepDynamicIndex(t *testing.T) {
+	sheetName := "Sheet1"
+	originData := []indexedViewModel{
+		{A: "a", B: "b", C: "c", Months: []aggregateEntry{{Month: "Jan", Value: 1}}},
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"all", nil, []string{"A", "Jan", "B", "C"}},
+		{"reordered", []Option{WithColumns("C", "A", "B", "Months")}, []string{"C", "A", "Jan", "B"}},
+		{"anchor not selected", []Option{WithColumns("C", "A", "Months")}, []string{"C", "A", "Jan"}},
+	}
+
+	mapper := NewExcelizeMapper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			defer f.Close()
+			view := mapper.With(c.opts...)
+			if err := view.SetData(f, sheetName, originData); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := f.GetRows(sheetName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows[0], c.want) {
+				t.Errorf("headers = %v, want %v", rows[0], c.want)
+			}
+		})
+	}
+}
+
+func TestHeaderTranslator(t *testing.T) {
+	sheetName := "Sheet1"
+	messages := map[string]map[string]string{
+		"ru": {"Name": "Имя", "Price": "Цена"},
+		"de": {"Name": "Name", "Price": "Preis"},
+	}
+	mapper := NewExcelizeMapper(
+		WithHeaderTranslator(func(key, lang string) string { return messages[lang][key] }),
+		WithLanguages("ru", "de"),
+	)
+
+	originData := []viewModel{{Name: "apple", Price: 2, Note: "fresh"}}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	ru := mapper.With(WithLanguage("ru"), WithColumns("Name", "Price", "Note"))
+	if err := ru.SetData(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Имя", "Цена", "Note"},
+		{"apple", "2", "fresh"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	var data []viewModel
+	de := mapper.With(WithLanguage("de"))
+	if err := de.GetData(f, sheetName, &data); err != nil {
+		t.Fatal(err)
+	}
+	wantData := []viewModel{{Name: "apple", Price: 2, Note: "fresh"}}
+	if !reflect.DeepEqual(data, wantData) {
+		t.Errorf("data = %+v, want %+v", data, wantData)
+	}
+}
+
+type priceModel struct {
+	Currency string  `excelize-mapper:"header:Currency"`
+	Amount   float64 `excelize-mapper:"header:Amount;format:money"`
+}
+
+func TestContextFormatter(t *testing.T) {
+	sheetName := "Sheet1"
+	money := func(ctx FormatContext) (interface{}, error) {
+		row := ctx.Row.(priceModel)
+		switch row.Currency {
+		case "USD":
+			return fmt.Sprintf("$%.2f", ctx.Value), nil
+		case "EUR":
+			return fmt.Sprintf("%.2f €", ctx.Value), nil
+		}
+		return nil, fmt.Errorf("row %d: unknown currency %q", ctx.RowIndex, row.Currency)
+	}
+	mapper := NewMapper[priceModel](WithContextFormatter("money", money))
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, []priceModel{{"USD", 1.5}, {"EUR", 2}}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Currency", "Amount"},
+		{"USD", "$1.50"},
+		{"EUR", "2.00 €"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	err = mapper.Write(f, sheetName, []priceModel{{"USD", 1}, {"XXX", 2}})
+	if err == nil || !strings.Contains(err.Error(), "cell B3") || !strings.Contains(err.Error(), `"XXX"`) {
+		t.Errorf("err = %v, want error of cell B3", err)
+	}
+}
+
+type typedCellModel struct {
+	Amount float64 `excelize-mapper:"header:Amount;format:round"`
+	Flag   string  `excelize-mapper:"header:Flag;format:bool"`
+	Name   string  `excelize-mapper:"header:Name;format:bold"`
+}
+
+func TestTypedFormatterValues(t *testing.T) {
+	sheetName := "Sheet1"
+	mapper := NewMapper[typedCellModel](
+		WithContextFormatter("round", ValueFormatter(func(v interface{}) interface{} {
+			return float64(int(v.(float64)*10+0.5)) / 10
+		})),
+		WithContextFormatter("bool", ValueFormatter(func(v interface{}) interface{} {
+			return v == "yes"
+		})),
+		WithContextFormatter("bold", ValueFormatter(func(v interface{}) interface{} {
+			return []excelize.RichTextRun{
+				{Text: v.(string), Font: &excelize.Font{Bold: true}},
+				{Text: "!"},
+			}
+		})),
+	)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, []typedCellModel{{1.26, "yes", "apple"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Amount", "Flag", "Name"},
+		{"1.3", "TRUE", "apple!"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	for cell, want := range map[string]excelize.CellType{"A2": excelize.CellTypeUnset, "B2": excelize.CellTypeBool} {
+		typ, err := f.GetCellType(sheetName, cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != want {
+			t.Errorf("type of %s = %v, want %v", cell, typ, want)
+		}
+	}
+
+	runs, err := f.GetCellRichText(sheetName, "C2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Font == nil || !runs[0].Font.Bold {
+		t.Errorf("runs = %+v", runs)
+	}
+}
+
+type builtinFormatModel struct {
+	Date    time.Time `excelize-mapper:"header:Date;format:time(02.01.2006)"`
+	Active  bool      `excelize-mapper:"header:Active;format:bool(Yes,No)"`
+	Sex     Sex       `excelize-mapper:"header:Sex;format:enum(0=Male,1=Female)"`
+	Price   float64   `excelize-mapper:"header:Price;format:fixed(2)"`
+	Amount  int       `excelize-mapper:"header:Amount;format:thousands(,)"`
+	Percent float64   `excelize-mapper:"header:Percent;format:percent(1)"`
+}
+
+func TestBuiltinFormatters(t *testing.T) {
+	sheetName := "Sheet1"
+	mapper := NewMapper[builtinFormatModel]()
+
+	originData := []builtinFormatModel{
+		{time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), true, 1, 1.5, 1234567, 0.125},
+		{time.Time{}, false, 0, -2, -1000, 1},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Date", "Active", "Sex", "Price", "Amount", "Percent"},
+		{"15.03.2024", "Yes", "Female", "1.50", "1,234,567", "12.5%"},
+		{"", "No", "Male", "-2.00", "-1,000", "100.0%"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	// number formatters write rounded numbers with number format
+	for cell, wantRaw := range map[string]string{"D2": "1.5", "E2": "1234567", "F2": "0.125"} {
+		raw, err := f.GetCellValue(sheetName, cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw != wantRaw {
+			t.Errorf("raw %s = %q, want %q", cell, raw, wantRaw)
+		}
+	}
+
+	if _, err := f.NewSheet("Rounded"); err != nil {
+		t.Fatal(err)
+	}
+	rounded := []builtinFormatModel{{Price: 1.234, Percent: 0.12345}}
+	if err := mapper.Write(f, "Rounded", rounded); err != nil {
+		t.Fatal(err)
+	}
+	for cell, wantRaw := range map[string]string{"D2": "1.23", "F2": "0.123"} {
+		raw, err := f.GetCellValue("Rounded", cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw != wantRaw {
+			t.Errorf("rounded raw %s = %q, want %q", cell, raw, wantRaw)
+		}
+	}
+
+	data, err := mapper.Read(f, sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, originData) {
+		t.Errorf("data = %+v, want %+v", data, originData)
+	}
+
+	// whole numbers have no decimal point in number format
+	type thousandsModel struct {
+		Amount float64 `excelize-mapper:"header:Amount;format:thousands()"`
+	}
+	if _, err := f.NewSheet("Thousands"); err != nil {
+		t.Fatal(err)
+	}
+	thousands := []thousandsModel{{1234567}, {1000.5}}
+	if err := NewMapper[thousandsModel]().Write(f, "Thousands", thousands); err != nil {
+		t.Fatal(err)
+	}
+	for cell, wantNumFmt := range map[string]string{"A2": "#,##0", "A3": "#,##0.##########"} {
+		styleID, err := f.GetCellStyle("Thousands", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// GetStyle reports wrong custom format of "#,##0", read it from styles part
+		numFmt := ""
+		for _, n := range f.Styles.NumFmts.NumFmt {
+			if n.NumFmtID == *f.Styles.CellXfs.Xf[styleID].NumFmtID {
+				numFmt = n.FormatCode
+			}
+		}
+		if numFmt != wantNumFmt {
+			t.Errorf("numfmt %s = %q, want %q", cell, numFmt, wantNumFmt)
+		}
+	}
+
+	type spaceModel struct {
+		Amount int `excelize-mapper:"header:Amount;format:thousands( )"`
+	}
+	if err := NewMapper[spaceModel]().Write(f, sheetName, []spaceModel{{1}}); err == nil {
+		t.Error("expected error for thousands separator other than ','")
+	}
+
+	// bare names are not built-ins, unregistered formatter is ignored
+	type bareModel struct {
+		Date   time.Time `excelize-mapper:"header:Date;format:time"`
+		Active bool      `excelize-mapper:"header:Active;format:bool"`
+		Sex    Sex       `excelize-mapper:"header:Sex;format:enum"`
+	}
+	bare := []bareModel{{time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), true, 1}}
+	if _, err := f.NewSheet("Bare"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewMapper[bareModel]().Write(f, "Bare", bare); err != nil {
+		t.Fatal(err)
+	}
+	for cell, wantRaw := range map[string]string{"A2": "45366", "B2": "1", "C2": "1"} {
+		raw, err := f.GetCellValue("Bare", cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw != wantRaw {
+			t.Errorf("bare raw %s = %q, want %q", cell, raw, wantRaw)
+		}
+	}
+
+	type badModel struct {
+		Active bool `excelize-mapper:"header:Active;format:bool(Yes)"`
+	}
+	if err := NewMapper[badModel]().Write(f, sheetName, []badModel{{}}); err == nil {
+		t.Error("expected error for bool formatter without false label")
+	}
+}
+
+type cents int64
+
+func (c cents) CellValue() (interface{}, error) {
+	if c < 0 {
+		return nil, fmt.Errorf("negative amount %d", c)
+	}
+	return float64(c) / 100, nil
+}
+
+func (c *cents) UnmarshalCell(text string) error {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return err
+	}
+	*c = cents(math.Round(f * 100))
+	return nil
+}
+
+type sku struct {
+	Group string
+	ID    int
+}
+
+func (s sku) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%s-%d", s.Group, s.ID)), nil
+}
+
+func (s *sku) UnmarshalText(text []byte) error {
+	group, id, _ := strings.Cut(string(text), "-")
+	s.Group = group
+	return json.Unmarshal([]byte(id), &s.ID)
+}
+
+type tagList []string
+
+func (l tagList) String() string {
+	return strings.Join(l, "|")
+}
+
+type level int
+
+func (l level) String() string {
+	return []string{"low", "high"}[l]
+}
+
+func (l *level) UnmarshalText(text []byte) error {
+	i := slices.Index([]string{"low", "high"}, string(text))
+	if i < 0 {
+		return fmt.Errorf("unknown level %q", text)
+	}
+	*l = level(i)
+	return nil
+}
+
+type interfaceModel struct {
+	Price   cents         `excelize-mapper:"header:Price"`
+	Timeout time.Duration `excelize-mapper:"header:Timeout"`
+	Level   level         `excelize-mapper:"header:Level"`
+	SKU     *sku          `excelize-mapper:"header:SKU"`
+	Tags    tagList       `excelize-mapper:"header:Tags"`
+	Created time.Time     `excelize-mapper:"header:Created"`
+}
+
+func TestInterfaceConversion(t *testing.T) {
+	sheetName := "Sheet1"
+	mapper := NewMapper[interfaceModel]()
+	created := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	originData := []interfaceModel{
+		{Price: 150, Timeout: time.Hour, Level: 1, SKU: &sku{"A", 7}, Tags: tagList{"x", "y"}, Created: created},
+		{Price: 5, Created: created},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Price", "Timeout", "Level", "SKU", "Tags", "Created"},
+		{"1.5", "1h0m0s", "high", "A-7", "x|y", "3/15/24 00:00"},
+		{"0.05", "0s", "low", "", "", "3/15/24 00:00"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	data, err := mapper.Read(f, sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stringer without unmarshaler is written only, Timeout is not read back
+	if len(data) != 2 || data[0].Price != 150 || data[0].Timeout != 0 || data[0].Level != 1 || data[0].SKU == nil || *data[0].SKU != (sku{"A", 7}) ||
+		!data[0].Created.Equal(created) || data[0].Tags != nil || data[1].Price != 5 || data[1].SKU != nil {
+		t.Errorf("data = %+v", data)
+	}
+
+	if err := mapper.Write(f, sheetName, []interfaceModel{{Price: -1}}); err == nil || !strings.Contains(err.Error(), "cell A2") {
+		t.Errorf("err = %v, want error of cell A2", err)
+	}
+}
+
+type dateEntry struct {
+	Name string    `excelize-mapper:"dynamicpos:"`
+	Date time.Time `excelize-mapper:"dynamicval:"`
+}
+
+type typeFormatModel struct {
+	Created time.Time   `excelize-mapper:"header:Created"`
+	Updated *time.Time  `excelize-mapper:"header:Updated"`
+	Logged  time.Time   `excelize-mapper:"header:Logged;format:time(15:04)"`
+	Dates   []dateEntry `excelize-mapper:"dynamic:{Name}"`
+}
+
+func TestTypeFormatter(t *testing.T) {
+	sheetName := "Sheet1"
+	layout := "2006/01/02"
+	mapper := NewMapper[typeFormatModel](
+		WithFormatterFor(func(t time.Time) string { return t.Format(layout) }),
+		WithParserFor(func(text string) (time.Time, error) { return time.Parse(layout, text) }),
+	)
+
+	day := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	originData := []typeFormatModel{
+		{Created: day, Updated: &day, Logged: day, Dates: []dateEntry{{"Due", day}}},
+		{Created: day, Logged: day},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Created", "Updated", "Logged", "Due"},
+		{"2024/03/15", "2024/03/15", "10:30", "2024/03/15"},
+		{"2024/03/15", "", "10:30"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	data, err := mapper.Read(f, sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	if len(data) != 2 || !data[0].Created.Equal(date) || data[0].Updated == nil || !data[0].Updated.Equal(date) ||
+		len(data[0].Dates) != 1 || !data[0].Dates[0].Date.Equal(date) || data[1].Updated != nil {
+		t.Errorf("data = %+v", data)
+	}
+}
+
+type Null[T any] struct {
+	V     T
+	Valid bool
+}
+
+type nullModel struct {
+	Name    sql.NullString  `excelize-mapper:"header:Name;default:unknown"`
+	Count   sql.NullInt64   `excelize-mapper:"header:Count"`
+	Created sql.NullTime    `excelize-mapper:"header:Created;format:time(2006-01-02)"`
+	Score   Null[float64]   `excelize-mapper:"header:Score"`
+	Note    *sql.NullString `excelize-mapper:"header:Note"`
+}
+
+func TestNullTypes(t *testing.T) {
+	sheetName := "Sheet1"
+	mapper := NewMapper[nullModel]()
+	day := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	originData := []nullModel{
+		{
+			Name:    sql.NullString{String: "apple", Valid: true},
+			Count:   sql.NullInt64{Int64: 0, Valid: true},
+			Created: sql.NullTime{Time: day, Valid: true},
+			Score:   Null[float64]{V: 1.5, Valid: true},
+			Note:    &sql.NullString{String: "fresh", Valid: true},
+		},
+		{Name: sql.NullString{String: "hidden"}},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Name", "Count", "Created", "Score", "Note"},
+		{"apple", "0", "2024-03-15", "1.5", "fresh"},
+		{"unknown"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	data, err := mapper.Read(f, sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantData := []nullModel{
+		originData[0],
+		{Name: sql.NullString{String: "unknown", Valid: true}},
+	}
+	if !reflect.DeepEqual(data, wantData) {
+		t.Errorf("data = %+v, want %+v", data, wantData)
+	}
+}
+
+type defaultModel struct {
+	Name    *string   `excelize-mapper:"header:Name;default:anonymous"`
+	Count   int       `excelize-mapper:"header:Count;default:10"`
+	Ratio   *float64  `excelize-mapper:"header:Ratio;default:0.5"`
+	Status  int       `excelize-mapper:"header:Status;default:N/A"`
+	Created time.Time `excelize-mapper:"header:Created;default:@today;format:time(2006-01-02)"`
+	Mention string    `excelize-mapper:"header:Mention;default:@@user"`
+}
+
+func TestTypedDefaults(t *testing.T) {
+	sheetName := "Sheet1"
+	today := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	mapper := NewMapper[defaultModel](WithDefaultFunc("today", func() interface{} { return today }))
+
+	name := "tom"
+	zero := 0.0
+	originData := []defaultModel{
+		{},
+		{Name: &name, Count: 1, Ratio: &zero, Status: 2, Created: today.AddDate(0, 0, 1), Mention: "bob"},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := mapper.Write(f, sheetName, originData); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Name", "Count", "Ratio", "Status", "Created", "Mention"},
+		{"anonymous", "10", "0.5", "N/A", "2024-03-15", "@user"},
+		{"tom", "1", "0", "2", "2024-03-16", "bob"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	for _, cell := range []string{"B2", "C2"} {
+		typ, err := f.GetCellType(sheetName, cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != excelize.CellTypeUnset {
+			t.Errorf("type of %s = %v, want number", cell, typ)
+		}
+	}
+
+	type badModel struct {
+		Created time.Time `excelize-mapper:"header:Created;default:@missing"`
+	}
+	if err := NewMapper[badModel]().Write(f, sheetName, []badModel{{}}); err == nil {
+		t.Error("expected error for unknown default func")
+	}
+}
+
+type omitEmptyModel struct {
+	Name    string     `excelize-mapper:"header:Name"`
+	Count   int        `excelize-mapper:"header:Count;omitempty"`
+	Active  bool       `excelize-mapper:"header:Active;omitempty"`
+	Created time.Time  `excelize-mapper:"header:Created;omitempty"`
+	Ratio   *float64   `excelize-mapper:"header:Ratio;omitempty"`
+	Updated *time.Time `excelize-mapper:"header:Updated"`
+	Total   int        `excelize-mapper:"header:Total"`
+	Sex     Sex        `excelize-mapper:"header:Sex;format:sex"`
+}
+
+func TestOmitEmpty(t *testing.T) {
+	sheetName := "Sheet1"
+	zero := 0.0
+	day := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	originData := []omitEmptyModel{
+		{Name: "apple", Ratio: &zero, Updated: &day},
+		{Name: "pear", Count: 1, Active: true, Created: day, Total: 2, Sex: 1},
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"tag", nil, []string{"apple", "", "", "", "0", "3/15/24 00:00", "0", "Male"}},
+		{"global", []Option{WithBlankZero()}, []string{"apple", "", "", "", "0", "3/15/24 00:00"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithFormatter("sex", func(value interface{}) string {
+					return []string{"Male", "Female"}[value.(Sex)]
+				}),
+				WithTypedParser("sex", func(text string) (Sex, error) {
+					return Sex(slices.Index([]string{"Male", "Female"}, text)), nil
+				}),
+			}, c.opts...)
+			mapper := NewMapper[omitEmptyModel](opts...)
+
+			f := excelize.NewFile()
+			defer f.Close()
+			if err := mapper.Write(f, sheetName, originData); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := f.GetRows(sheetName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows[1], c.want) {
+				t.Errorf("row = %q, want %q", rows[1], c.want)
+			}
+
+			data, err := mapper.Read(f, sheetName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data, originData) {
+				t.Errorf("data = %+v, want %+v", data, originData)
+			}
+		})
+	}
+}
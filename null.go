@@ -0,0 +1,94 @@
+package excelizemapper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+var (
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// nullableFields returns field indexes of nullable struct, e.g. sql.NullString,
+// sql.Null[T] or alike generic type: struct of "Valid bool" and a value field.
+func nullableFields(t reflect.Type) (value, valid int, ok bool) {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return 0, 0, false
+	}
+	for i := 0; i < 2; i++ {
+		field := t.Field(i)
+		if field.Name == "Valid" && field.Type.Kind() == reflect.Bool {
+			other := t.Field(1 - i)
+			return 1 - i, i, other.IsExported()
+		}
+	}
+	return 0, 0, false
+}
+
+// nullValue returns inner value of nullable struct, invalid Value if it is null
+func nullValue(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() {
+		return v, false
+	}
+	value, valid, ok := nullableFields(v.Type())
+	if !ok {
+		return v, false
+	}
+	if !v.Field(valid).Bool() {
+		return reflect.Value{}, true
+	}
+	return v.Field(value), true
+}
+
+// setNullable sets value field of nullable struct by set and marks it valid,
+// isNull resets field to null. It reports whether field is nullable.
+func setNullable(field reflect.Value, set func(inner reflect.Value) error, isNull bool) (bool, error) {
+	t := field.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	value, valid, ok := nullableFields(t)
+	if !ok {
+		return false, nil
+	}
+	if isNull {
+		field.Set(reflect.Zero(field.Type()))
+		return true, nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(t))
+		}
+		field = field.Elem()
+	}
+	if err := set(field.Field(value)); err != nil {
+		return true, err
+	}
+	field.Field(valid).SetBool(true)
+	return true, nil
+}
+
+// scanCell sets field by sql.Scanner, nil pointer field is allocated
+func scanCell(field reflect.Value, text string) (bool, error) {
+	t := field.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !implements(t, scannerType) {
+		return false, nil
+	}
+	if text == "" {
+		return true, nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(t))
+		}
+		field = field.Elem()
+	}
+	return true, field.Addr().Interface().(sql.Scanner).Scan(text)
+}
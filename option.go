@@ -1,16 +1,91 @@
 package excelizemapper
 
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/xuri/excelize/v2"
+)
+
 type Format func(interface{}) string
 
+// FormatContext describes cell being formatted by ContextFormat
+type FormatContext struct {
+	// Row is the row struct, Value is the field value (after default is applied)
+	Row    interface{}
+	Value  interface{}
+	Column Column
+	// RowIndex is index of row in data, Cell is cell name, e.g. "B2"
+	RowIndex int
+	Cell     string
+}
+
+// ContextFormat is a formatter that can see the whole row and fail,
+// SetData stops on error and wraps it with the cell name.
+//
+// Numbers, bools and time.Time are written with native cell type,
+// []excelize.RichTextRun is written as rich text.
+type ContextFormat func(ctx FormatContext) (interface{}, error)
+
+// Parse converts cell text back to field value, it is the reverse of Format with the same name.
+type Parse func(string) (interface{}, error)
+
 type options struct {
 	tagKey       string
 	autoSort     bool
 	defaultWidth float64
+	blankZero    bool
+	strictTags   bool
 	formatterMap map[string]Format
+	contextMap   map[string]ContextFormat
+	parserMap    map[string]Parse
+	factories    map[string]FormatterFactory
+	typeFormats  map[reflect.Type]Format
+	typeParsers  map[reflect.Type]Parse
+	defaultFuncs map[string]DefaultFunc
+	factoryCache *sync.Map
+	comparators  map[string]DynamicComparator
+
+	dynamicDomains map[string]func() []DynamicKey
+	dynamicFills   map[string]interface{}
+
+	styles map[string]*excelize.Style
+
+	schemas map[reflect.Type]schemaProvider
+
+	view           string
+	columns        []string
+	withoutColumns []string
+
+	translator HeaderTranslator
+	language   string
+	languages  []string
 }
 
 type Option func(o *options)
 
+func (o options) clone() options {
+	o.formatterMap = maps.Clone(o.formatterMap)
+	o.contextMap = maps.Clone(o.contextMap)
+	o.parserMap = maps.Clone(o.parserMap)
+	o.factories = maps.Clone(o.factories)
+	o.typeFormats = maps.Clone(o.typeFormats)
+	o.typeParsers = maps.Clone(o.typeParsers)
+	o.defaultFuncs = maps.Clone(o.defaultFuncs)
+	o.factoryCache = newFactoryCache()
+	o.comparators = maps.Clone(o.comparators)
+	o.dynamicDomains = maps.Clone(o.dynamicDomains)
+	o.dynamicFills = maps.Clone(o.dynamicFills)
+	o.styles = maps.Clone(o.styles)
+	o.schemas = maps.Clone(o.schemas)
+	o.columns = slices.Clone(o.columns)
+	o.withoutColumns = slices.Clone(o.withoutColumns)
+	o.languages = slices.Clone(o.languages)
+	return o
+}
+
 // WithTagKey set tag key
 //
 // default is "excelize-mapper"
@@ -27,6 +102,44 @@ func WithFormatter(name string, format Format) Option {
 	}
 }
 
+// WithContextFormatter set context formatter, it takes precedence over formatter of the same name
+func WithContextFormatter(name string, format ContextFormat) Option {
+	return func(o *options) {
+		o.contextMap[name] = format
+	}
+}
+
+// ValueFormatter converts formatter returning typed cell value to ContextFormat
+func ValueFormatter(format func(interface{}) interface{}) ContextFormat {
+	return func(ctx FormatContext) (interface{}, error) {
+		return format(ctx.Value), nil
+	}
+}
+
+// WithParser set parser used by GetData for columns with formatter of the same name
+//
+// columns with formatter but without parser are not read.
+func WithParser(name string, parse Parse) Option {
+	return func(o *options) {
+		o.parserMap[name] = parse
+	}
+}
+
+// WithTypeFormatter set formatter of all columns of type t or pointer to t
+// without format tag, dynamic values included.
+func WithTypeFormatter(t reflect.Type, format Format) Option {
+	return func(o *options) {
+		o.typeFormats[t] = format
+	}
+}
+
+// WithTypeParser set parser of all columns of type t or pointer to t without format tag
+func WithTypeParser(t reflect.Type, parse Parse) Option {
+	return func(o *options) {
+		o.typeParsers[t] = parse
+	}
+}
+
 // WithAutoSort set auto sort
 //
 // if auto sort is false, use tag index. default is true.
@@ -36,9 +149,97 @@ func WithAutoSort(autoSort bool) Option {
 	}
 }
 
+// WithBlankZero writes zero values of all columns as empty cells,
+// same as "omitempty" tag of every column.
+func WithBlankZero() Option {
+	return func(o *options) {
+		o.blankZero = true
+	}
+}
+
+// WithStrictTags makes malformed tags, unknown keys, invalid values and
+// unregistered formatters schema errors, by default they are ignored.
+func WithStrictTags() Option {
+	return func(o *options) {
+		o.strictTags = true
+	}
+}
+
 // WithDefaultWidth set default width
 func WithDefaultWidth(width float64) Option {
 	return func(o *options) {
 		o.defaultWidth = width
 	}
 }
+
+// WithDynamicComparator set dynamic header comparator
+//
+// use it in struct by excelize-mapper:"dynamic:...;sort:name;"
+func WithDynamicComparator(name string, cmp DynamicComparator) Option {
+	return func(o *options) {
+		o.comparators[name] = cmp
+	}
+}
+
+// WithDynamicDomain set full header domain of dynamic field
+//
+// field is a path to dynamic slice, e.g. "Dynamic" or "Embedded.Dynamic".
+// Every key of domain is emitted as a column in the given order, even if
+// no row has a value for it. Entries outside of domain are not written.
+func WithDynamicDomain(field string, domain ...DynamicKey) Option {
+	return WithDynamicDomainFunc(field, func() []DynamicKey {
+		return domain
+	})
+}
+
+// WithDynamicDomainFunc set provider of full header domain of dynamic field
+//
+// provider is called once per SetData call, see WithDynamicDomain.
+func WithDynamicDomainFunc(field string, provider func() []DynamicKey) Option {
+	return func(o *options) {
+		o.dynamicDomains[field] = provider
+	}
+}
+
+// WithDynamicFill set value for dynamic cells without entry
+//
+// default is empty cell.
+func WithDynamicFill(field string, value interface{}) Option {
+	return func(o *options) {
+		o.dynamicFills[field] = value
+	}
+}
+
+// WithStyle set cell style
+//
+// use it in struct by excelize-mapper:"style:name;"
+func WithStyle(name string, style *excelize.Style) Option {
+	return func(o *options) {
+		o.styles[name] = style
+	}
+}
+
+// WithView set view, only columns without views tag or with the view in
+// excelize-mapper:"views:admin,finance;" are written and read.
+func WithView(name string) Option {
+	return func(o *options) {
+		o.view = name
+	}
+}
+
+// WithColumns set columns to write and read in the given order
+//
+// names are field names, e.g. "Name" or "Embedded.Name", dynamic fields
+// are selected by the same names.
+func WithColumns(fieldNames ...string) Option {
+	return func(o *options) {
+		o.columns = fieldNames
+	}
+}
+
+// WithoutColumns set columns to skip
+func WithoutColumns(fieldNames ...string) Option {
+	return func(o *options) {
+		o.withoutColumns = fieldNames
+	}
+}
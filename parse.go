@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type parser struct {
@@ -22,80 +23,239 @@ type parser struct {
 	tagDynamicKey    string
 	tagDynamicPosKey string
 	tagDynamicValKey string
+	tagSortKey       string
+	tagAggregateKey  string
+	tagNumFmtKey     string
+	tagStyleKey      string
+	tagAfterKey      string
+	tagViewsKey      string
+	tagOmitEmptyKey  string
+
+	// strict reports malformed tags, unknown keys and bad values
+	strict bool
 }
 
-func (p *parser) parse(data interface{}) ([]Column, *DynamicRules, error) {
-	dv := reflect.ValueOf(data)
-	di := reflect.Indirect(dv)
-	dk := di.Kind()
-
-	if dk != reflect.Array && dk != reflect.Slice {
-		return nil, nil, fmt.Errorf("data not array or slice")
-	}
-
-	itemType := di.Type().Elem()
-	if itemType.Kind() == reflect.Ptr {
-		itemType = itemType.Elem()
+// compileType parses schema of struct type, use cached parseType instead
+func (p *parser) compileType(itemType reflect.Type) ([]Column, []*DynamicRules, error) {
+	if itemType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("data item %s is not struct", itemType)
 	}
 
-	cols, rules, err := p.parseFieldsRecursive(itemType, "")
+	autoIndex := 0
+	cols, rules, err := p.parseFieldsRecursive(itemType, "", nil, &autoIndex)
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := validateColumns(cols); err != nil {
+		return nil, nil, err
+	}
 
-	sort.Slice(cols, func(i, j int) bool {
-		return cols[i].ColumnIndex < cols[j].ColumnIndex
-	})
+	sortColumns(cols)
 
 	return cols, rules, nil
 }
 
-func (p *parser) parseTags(tag string) map[string]string {
-	kv := make(map[string]string)
-	tags := strings.Split(tag, p.tagDelim)
-	for _, t := range tags {
-		t = strings.TrimSpace(t)
-		if t == "" {
-			continue
+type DynamicRules struct {
+	Mappings        map[string]string
+	PosFields       []string
+	ValueField      string
+	ParentFieldName string
+	ParentRule      string
+	SortRule        string
+	AggregateRule   string
+	// Value holds cell settings applied to every generated column.
+	Value Column
+	// Index is a static column position where generated columns are placed,
+	// static columns from this position are shifted right. -1 means after all columns.
+	Index int
+	// After is a static field name generated columns are placed after.
+	After string
+	// Views holds views generated columns are visible in, empty for all.
+	Views []string
+
+	template  headerTemplate
+	groups    []headerTemplate
+	entryType reflect.Type
+	sortMode  string
+	sortKeys  []dynamicSortKey
+	aggregate aggregatePolicy
+	// resolveEntry compiles rules of interface entries for entry type,
+	// entryType of such rules is nil until they are resolved
+	resolveEntry func(t reflect.Type) (*DynamicRules, error)
+
+	// precomputed index paths, fieldIndex is relative to row, others to entry
+	fieldIndex []int
+	posIndex   []int
+	valueIndex int
+}
+
+func (dr *DynamicRules) getKey(entryVal reflect.Value) DynamicKey {
+	key := make(DynamicKey, len(dr.PosFields))
+	for i, field := range dr.PosFields {
+		var val interface{}
+		if fieldVal := reflect.Indirect(entryVal.Field(dr.posIndex[i])); fieldVal.IsValid() {
+			val = fieldVal.Interface()
 		}
+		key[field] = val
+	}
+	return key
+}
 
-		kvSlice := strings.SplitN(t, ":", 2)
-		if len(kvSlice) != 2 {
-			continue
+// getValue returns value field of entry, invalid value if there is none
+func (dr *DynamicRules) getValue(entryVal reflect.Value) reflect.Value {
+	if dr.valueIndex < 0 {
+		return reflect.Value{}
+	}
+	return entryVal.Field(dr.valueIndex)
+}
+
+func (dr *DynamicRules) getReplacedHeader(entryVal reflect.Value, formatters formatterLookup) (DynamicHeader, error) {
+	return dr.renderHeader(dr.getKey(entryVal), formatters)
+}
+
+func (dr *DynamicRules) renderHeader(key DynamicKey, formatters formatterLookup) (DynamicHeader, error) {
+	colHeader, err := dr.template.render(key, formatters)
+	if err != nil {
+		return DynamicHeader{}, fmt.Errorf("dynamic field %s: %w", dr.ParentFieldName, err)
+	}
+	slog.Debug("colHeader", "name", colHeader)
+
+	var groups []string
+	for _, group := range dr.groups {
+		groupHeader, err := group.render(key, formatters)
+		if err != nil {
+			return DynamicHeader{}, fmt.Errorf("dynamic field %s: %w", dr.ParentFieldName, err)
 		}
+		groups = append(groups, groupHeader)
+	}
+
+	return DynamicHeader{Header: colHeader, Groups: groups, Key: key}, nil
+}
 
-		kv[kvSlice[0]] = kvSlice[1]
+// Levels returns count of header rows used by generated columns
+func (dr *DynamicRules) Levels() int {
+	if dr.entryType == nil {
+		return len(splitHeaderLevels(dr.ParentRule))
 	}
-	return kv
+	return len(dr.groups) + 1
 }
 
-type DynamicRules struct {
-	Mappings        map[string]string
-	ValueField      string
-	ParentFieldName string
-	ParentRule      string
+// getEntries returns non-nil entries of dynamic slice of row
+func (dr *DynamicRules) getEntries(rowVal reflect.Value) ([]reflect.Value, error) {
+	sliceEntries := indirectValue(fieldByIndex(rowVal, dr.fieldIndex))
+	if !sliceEntries.IsValid() {
+		return nil, nil
+	}
+	if sliceEntries.Kind() != reflect.Slice && sliceEntries.Kind() != reflect.Array {
+		return nil, fmt.Errorf("dynamic field %s: %s is not slice", dr.ParentFieldName, sliceEntries.Type())
+	}
+
+	entries := make([]reflect.Value, 0, sliceEntries.Len())
+	for i := 0; i < sliceEntries.Len(); i++ {
+		entry := indirectValue(sliceEntries.Index(i))
+		if !entry.IsValid() {
+			continue
+		}
+		if entry.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("dynamic field %s: entry %d is %s, not struct", dr.ParentFieldName, i, entry.Type())
+		}
+		if entry.Type() != dr.entryType {
+			return nil, fmt.Errorf("dynamic field %s: entry %d is %s, want %s", dr.ParentFieldName, i, entry.Type(), dr.entryType)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
 }
 
-func (dr *DynamicRules) getReplacedHeader(entryVal reflect.Value) string {
-	colHeader := dr.ParentRule
-	for pos, field := range dr.Mappings {
-		slog.Debug("field", "name", field)
+// getDynamicRules parses entry type of dynamic slice. Entry must be struct or
+// pointer to struct, rules of interface entries are compiled when data is written.
+func (p *parser) getDynamicRules(dynamicSlice reflect.StructField, prefix string, indexPrefix []int, tags map[string]string) (*DynamicRules, error) {
+	t := dynamicSlice.Type.Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return p.interfaceDynamicRules(dynamicSlice, prefix, indexPrefix, tags)
+	}
+	return p.compileDynamicRules(dynamicSlice, t, prefix, indexPrefix, tags)
+}
 
-		fieldRef := entryVal.FieldByName(field)
-		slog.Debug("fieldRef", "name", fieldRef.Interface())
+// interfaceDynamicRules returns rules of interface entries, they are compiled
+// for type of the first non-nil entry by resolveEntry. Interface entries can't
+// be read back, their columns are skipped by GetData.
+func (p *parser) interfaceDynamicRules(dynamicSlice reflect.StructField, prefix string, indexPrefix []int, tags map[string]string) (*DynamicRules, error) {
+	valColumn := Column{Tags: make(map[string]string)}
+	if err := p.applyCellTags(&valColumn, tags); err != nil {
+		return nil, fmt.Errorf("field %s: %w", dynamicSlice.Name, err)
+	}
+	index, after, err := p.dynamicPlacement(dynamicSlice.Name, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := *p
+	var compiled sync.Map
+	return &DynamicRules{
+		ParentFieldName: prefix + dynamicSlice.Name,
+		ParentRule:      tags[p.tagDynamicKey],
+		SortRule:        tags[p.tagSortKey],
+		AggregateRule:   tags[p.tagAggregateKey],
+		Value:           valColumn,
+		Index:           index,
+		After:           after,
+		Views:           parseList(tags[p.tagViewsKey]),
+		fieldIndex:      joinIndex(indexPrefix, dynamicSlice.Index),
+		valueIndex:      -1,
+		resolveEntry: func(t reflect.Type) (*DynamicRules, error) {
+			if cached, ok := compiled.Load(t); ok {
+				return cached.(*DynamicRules), nil
+			}
+			rules, err := compiler.compileDynamicRules(dynamicSlice, t, prefix, indexPrefix, tags)
+			if err != nil {
+				return nil, err
+			}
+			cached, _ := compiled.LoadOrStore(t, rules)
+			return cached.(*DynamicRules), nil
+		},
+	}, nil
+}
 
-		colHeader = strings.ReplaceAll(colHeader, pos, fmt.Sprint(fieldRef.Interface()))
-		slog.Debug("colHeader", "name", colHeader)
+// dynamicPlacement parses "index" and "after" tags of dynamic field
+func (p *parser) dynamicPlacement(name string, tags map[string]string) (int, string, error) {
+	index := -1
+	if indexStr, ok := tags[p.tagIndexKey]; ok {
+		var err error
+		index, err = strconv.Atoi(indexStr)
+		if err != nil || index < 0 {
+			return 0, "", fmt.Errorf("invalid index value %q for field %s", indexStr, name)
+		}
+	}
+	after := tags[p.tagAfterKey]
+	if index >= 0 && after != "" {
+		return 0, "", fmt.Errorf("both index and after set for field %s", name)
 	}
-	return colHeader
+	return index, after, nil
 }
 
-func (p *parser) getDynamicRules(dynamicSlice reflect.StructField) *DynamicRules {
+// compileDynamicRules parses dynamic rules of entry struct type t
+func (p *parser) compileDynamicRules(dynamicSlice reflect.StructField, t reflect.Type, prefix string, indexPrefix []int, tags map[string]string) (*DynamicRules, error) {
 
 	mappings := make(map[string]string)
+	var posFields []string
+	var posIndex []int
 	var valField string
+	valIndex := -1
+
+	// settings of parent "dynamic" tag are overridden by "dynamicval" field
+	valColumn := Column{Tags: make(map[string]string)}
+	if err := p.applyCellTags(&valColumn, tags); err != nil {
+		return nil, fmt.Errorf("field %s: %w", dynamicSlice.Name, err)
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dynamic field %s: entry %s is not struct", dynamicSlice.Name, t)
+	}
 
-	t := dynamicSlice.Type.Elem()
 	for i := 0; i < t.NumField(); i++ {
 
 		field := t.Field(i)
@@ -104,7 +264,10 @@ func (p *parser) getDynamicRules(dynamicSlice reflect.StructField) *DynamicRules
 			continue
 		}
 
-		tags := p.getTagsByKey(field)
+		tags, err := p.getTagsByKey(field)
+		if err != nil {
+			return nil, fmt.Errorf("dynamic field %s: %w", dynamicSlice.Name, err)
+		}
 		slog.Debug("parsed tags", "value", tags)
 
 		if tags == nil {
@@ -112,65 +275,181 @@ func (p *parser) getDynamicRules(dynamicSlice reflect.StructField) *DynamicRules
 		}
 
 		if posKey, ok := tags[p.tagDynamicPosKey]; ok {
+			if posKey == "" {
+				posKey = field.Name
+			}
 			mappings[posKey] = field.Name
+			posFields = append(posFields, field.Name)
+			posIndex = append(posIndex, i)
 			continue
 		}
 
 		if _, ok := tags[p.tagDynamicValKey]; ok {
 			valField = field.Name
+			valIndex = i
+			if err := p.applyCellTags(&valColumn, tags); err != nil {
+				return nil, fmt.Errorf("dynamic field %s: field %s: %w", dynamicSlice.Name, field.Name, err)
+			}
 			slog.Debug("found valField", "value", valField)
 			continue
 		}
 
 	}
 
+	parentRule := tags[p.tagDynamicKey]
+	keys := legacyKeys(parentRule, mappings)
+	used := make(map[string]bool)
+	var templates []headerTemplate
+	for _, rule := range splitHeaderLevels(parentRule) {
+		tpl, err := compileHeaderTemplate(rule, keys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dynamic rule for field %s: %w", dynamicSlice.Name, err)
+		}
+		if err := tpl.resolve(mappings); err != nil {
+			return nil, fmt.Errorf("invalid dynamic rule for field %s: %w", dynamicSlice.Name, err)
+		}
+		for _, seg := range tpl.segments {
+			used[seg.key] = true
+		}
+		templates = append(templates, tpl)
+	}
+	// header without a dynamicpos field merges entries into one column, it is
+	// intended for rollups with "aggregate", strict parser reports it
+	for _, posField := range posFields {
+		if p.strict && !used[posField] {
+			return nil, fmt.Errorf("invalid dynamic rule for field %s: dynamicpos field %s is not used by rule %q",
+				dynamicSlice.Name, posField, parentRule)
+		}
+	}
+
+	sortRule := tags[p.tagSortKey]
+	sortMode, sortKeys, err := parseDynamicSort(sortRule, posFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sort rule for field %s: %w", dynamicSlice.Name, err)
+	}
+
+	aggregateRule := tags[p.tagAggregateKey]
+	aggregate, err := parseAggregatePolicy(aggregateRule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aggregate rule for field %s: %w", dynamicSlice.Name, err)
+	}
+
+	valColumn.FieldName = valField
+	if valIndex >= 0 {
+		valColumn.setValueType(t.Field(valIndex).Type)
+	}
+
+	index, after, err := p.dynamicPlacement(dynamicSlice.Name, tags)
+	if err != nil {
+		return nil, err
+	}
+
 	return &DynamicRules{
 		Mappings:        mappings,
+		PosFields:       posFields,
 		ValueField:      valField,
-		ParentFieldName: dynamicSlice.Name,
-	}
+		ParentFieldName: prefix + dynamicSlice.Name,
+		ParentRule:      parentRule,
+		SortRule:        sortRule,
+		AggregateRule:   aggregateRule,
+		Value:           valColumn,
+		Index:           index,
+		After:           after,
+		Views:           parseList(tags[p.tagViewsKey]),
+		template:        templates[len(templates)-1],
+		groups:          templates[:len(templates)-1],
+		entryType:       t,
+		fieldIndex:      joinIndex(indexPrefix, dynamicSlice.Index),
+		posIndex:        posIndex,
+		valueIndex:      valIndex,
+		sortMode:        sortMode,
+		sortKeys:        sortKeys,
+		aggregate:       aggregate,
+	}, nil
 
 }
 
-func (p *parser) getTagsByKey(dynamicSlice reflect.StructField) map[string]string {
-	fullTagVal := dynamicSlice.Tag.Get(p.tagKey)
-	if fullTagVal == "" {
-		return nil
+// applyCellTags set cell settings present in tags, invalid values are
+// ignored unless parser is strict.
+func (p *parser) applyCellTags(col *Column, tags map[string]string) error {
+	if col.Tags == nil {
+		col.Tags = make(map[string]string, len(tags))
+	}
+	for key, val := range tags {
+		col.Tags[key] = val
 	}
 
-	return p.parseTags(fullTagVal)
+	if widthStr, ok := tags[p.tagWidthKey]; ok {
+		val, err := parseWidth(widthStr)
+		if err == nil {
+			col.ColumnWidth = val
+		} else if p.strict {
+			return fmt.Errorf("key %q: %w", p.tagWidthKey, err)
+		}
+	}
+	if val, ok := tags[p.tagDefaultKey]; ok {
+		col.DefaultValue = val
+	}
+	if val, ok := tags[p.tagFormatKey]; ok {
+		col.FormatterKey = val
+	}
+	if val, ok := tags[p.tagNumFmtKey]; ok {
+		col.NumFmt = val
+	}
+	if val, ok := tags[p.tagStyleKey]; ok {
+		col.StyleKey = val
+	}
+	if flagStr, ok := tags[p.tagOmitEmptyKey]; ok {
+		val, err := parseFlag(flagStr)
+		if err == nil {
+			col.OmitEmpty = val
+		} else if p.strict {
+			return fmt.Errorf("key %q: %w", p.tagOmitEmptyKey, err)
+		}
+	}
+	return nil
 }
 
-func (p *parser) parseFieldsRecursive(t reflect.Type, prefix string) ([]Column, *DynamicRules, error) {
+// parseFieldsRecursive parses fields of t, autoIndex is shared with embedded
+// structs so their columns get positions unique across the whole struct.
+func (p *parser) parseFieldsRecursive(t reflect.Type, prefix string, indexPrefix []int, autoIndex *int) ([]Column, []*DynamicRules, error) {
 	var cols []Column
-	var dynamicRules *DynamicRules
-	autoIndex := 0
+	var dynamicRules []*DynamicRules
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 
-		if !field.IsExported() {
-			continue
-		}
-
+		// Embedded structs are walked even when their type is unexported,
+		// their exported fields are still promoted to the parent.
 		if field.Type.Kind() == reflect.Struct && field.Anonymous {
-			nestedCols, _, err := p.parseFieldsRecursive(field.Type, prefix+field.Name+".")
+			nestedCols, nestedRules, err := p.parseFieldsRecursive(field.Type, prefix+field.Name+".", joinIndex(indexPrefix, field.Index), autoIndex)
 			if err != nil {
 				return nil, nil, err
 			}
 			cols = append(cols, nestedCols...)
+			dynamicRules = append(dynamicRules, nestedRules...)
 			continue
 		}
 
-		tags := p.getTagsByKey(field)
+		if !field.IsExported() {
+			continue
+		}
+
+		tags, err := p.getTagsByKey(field)
+		if err != nil {
+			return nil, nil, err
+		}
 		if tags == nil {
 			continue
 		}
 
-		parentRule, hasDynamicTag := tags[p.tagDynamicKey]
-		if field.Type.Kind() == reflect.Slice && hasDynamicTag {
-			dynamicRules = p.getDynamicRules(field)
-			dynamicRules.ParentRule = parentRule
+		_, hasDynamicTag := tags[p.tagDynamicKey]
+		if hasDynamicTag && (field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Array) {
+			rules, err := p.getDynamicRules(field, prefix, indexPrefix, tags)
+			if err != nil {
+				return nil, nil, err
+			}
+			dynamicRules = append(dynamicRules, rules)
 			continue
 		}
 
@@ -179,10 +458,10 @@ func (p *parser) parseFieldsRecursive(t reflect.Type, prefix string) ([]Column,
 			continue
 		}
 
-		var colWidth float64
-		if widthStr, ok := tags[p.tagWidthKey]; ok {
-			if val, err := strconv.ParseFloat(widthStr, 64); err == nil {
-				colWidth = val
+		// index is validated even if it is not used
+		if indexStr, ok := tags[p.tagIndexKey]; ok && p.strict && p.autosort {
+			if _, err := parseIndex(indexStr); err != nil {
+				return nil, nil, fmt.Errorf("field %s: key %q: %w", field.Name, p.tagIndexKey, err)
 			}
 		}
 
@@ -198,21 +477,46 @@ func (p *parser) parseFieldsRecursive(t reflect.Type, prefix string) ([]Column,
 			}
 			colIndex = idx
 		} else {
-			colIndex = autoIndex
-			autoIndex++
+			colIndex = *autoIndex
+			*autoIndex++
 		}
 
 		col := Column{
-			ColumnIndex:  colIndex,
-			HeaderName:   header,
-			ColumnWidth:  colWidth,
-			DefaultValue: tags[p.tagDefaultKey],
-			FormatterKey: tags[p.tagFormatKey],
-			FieldName:    prefix + field.Name,
+			ColumnIndex: colIndex,
+			HeaderName:  header,
+			FieldName:   prefix + field.Name,
+			Views:       parseList(tags[p.tagViewsKey]),
+			fieldIndex:  joinIndex(indexPrefix, field.Index),
+		}
+		if err := p.applyCellTags(&col, tags); err != nil {
+			return nil, nil, fmt.Errorf("field %s: %w", field.Name, err)
 		}
+		col.setValueType(field.Type)
 
 		cols = append(cols, col)
 	}
 
 	return cols, dynamicRules, nil
 }
+
+// joinIndex returns new index path, prefix is never shared with result
+func joinIndex(prefix, index []int) []int {
+	return append(append(make([]int, 0, len(prefix)+len(index)), prefix...), index...)
+}
+
+func sortColumns(cols []Column) {
+	sort.SliceStable(cols, func(i, j int) bool {
+		return cols[i].ColumnIndex < cols[j].ColumnIndex
+	})
+}
+
+// parseList parses comma separated tag value
+func parseList(val string) []string {
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,493 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// GetData reads sheet into slice pointed by slicePtr.
+//
+// Columns are matched by header, dynamic columns are matched by their rule
+// and parsed back into dynamic entries. Blank rows are skipped.
+func (em *ExcelizeMapper) GetData(f *excelize.File, sheet string, slicePtr interface{}) error {
+	pv := reflect.ValueOf(slicePtr)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("data not pointer to slice")
+	}
+
+	itemType := pv.Elem().Type().Elem()
+	if itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+
+	columns, dynamicRules, err := em.schemaOf(itemType)
+	if err != nil {
+		return err
+	}
+
+	return em.getData(f, sheet, columns, dynamicRules, pv.Elem())
+}
+
+// readDynamicColumn is a sheet column matched by dynamic rule, entry holds
+// dynamicpos values parsed from header.
+type readDynamicColumn struct {
+	col   int
+	rules *DynamicRules
+	entry reflect.Value
+}
+
+// getData reads rows of sheet into slice value out by parsed schema
+func (em *ExcelizeMapper) getData(f *excelize.File, sheet string, columns []Column, dynamicRules []*DynamicRules, out reflect.Value) error {
+	columns, dynamicRules = em.selectColumns(columns, dynamicRules)
+
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("excelize GetRows error: %w", err)
+	}
+
+	headerRows := 1
+	for _, rules := range dynamicRules {
+		headerRows = max(headerRows, rules.Levels())
+	}
+
+	result := reflect.MakeSlice(out.Type(), 0, max(len(rows)-headerRows, 0))
+	if len(rows) < headerRows {
+		out.Set(result)
+		return nil
+	}
+
+	grid, err := readHeaders(f, sheet, rows[:headerRows])
+	if err != nil {
+		return err
+	}
+
+	taken := make(map[int]bool)
+	static := make([]int, len(columns))
+	for i, column := range columns {
+		static[i] = -1
+		for col, header := range grid[0] {
+			if !taken[col] && em.acceptsHeader(column, header) {
+				static[i] = col
+				taken[col] = true
+				break
+			}
+		}
+	}
+
+	var dynamicCols []readDynamicColumn
+	for col := range grid[0] {
+		if taken[col] {
+			continue
+		}
+		for _, rules := range dynamicRules {
+			entry, ok, err := em.matchDynamicHeader(rules, grid, col)
+			if err != nil {
+				return err
+			}
+			if ok {
+				dynamicCols = append(dynamicCols, readDynamicColumn{col: col, rules: rules, entry: entry})
+				break
+			}
+		}
+	}
+
+	elemType := out.Type().Elem()
+	rowType := elemType
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
+	}
+
+	for rowIndex := headerRows; rowIndex < len(rows); rowIndex++ {
+		row := rows[rowIndex]
+		if isBlankRow(row) {
+			continue
+		}
+
+		rowPtr := reflect.New(rowType)
+		rowVal := rowPtr.Elem()
+
+		for i, column := range columns {
+			if static[i] < 0 || static[i] >= len(row) {
+				continue
+			}
+
+			if err := em.setColumnValue(&column, rowVal, row[static[i]]); err != nil {
+				return cellError(static[i], rowIndex, err)
+			}
+		}
+
+		for _, dc := range dynamicCols {
+			if dc.col >= len(row) || row[dc.col] == "" {
+				continue
+			}
+
+			entry := reflect.New(dc.rules.entryType).Elem()
+			entry.Set(dc.entry)
+			if dc.rules.ValueField != "" {
+				if err := em.setFieldValue(dc.rules.getValue(entry), dc.rules.Value, row[dc.col]); err != nil {
+					return cellError(dc.col, rowIndex, err)
+				}
+			}
+
+			sliceField := fieldByIndexForSet(rowVal, dc.rules.fieldIndex)
+			if sliceField.Kind() != reflect.Slice {
+				continue
+			}
+			if sliceField.Type().Elem().Kind() == reflect.Ptr {
+				entry = entry.Addr()
+			}
+			sliceField.Set(reflect.Append(sliceField, entry))
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			result = reflect.Append(result, rowPtr)
+		} else {
+			result = reflect.Append(result, rowVal)
+		}
+	}
+
+	out.Set(result)
+	return nil
+}
+
+// readHeaders returns header rows with values of merged cells copied to every cell of merged range
+func readHeaders(f *excelize.File, sheet string, headerRows [][]string) ([][]string, error) {
+	width := 0
+	for _, row := range headerRows {
+		width = max(width, len(row))
+	}
+
+	grid := make([][]string, len(headerRows))
+	for i, row := range headerRows {
+		grid[i] = make([]string, width)
+		copy(grid[i], row)
+	}
+
+	if len(headerRows) == 1 {
+		return grid, nil
+	}
+
+	mergeCells, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("excelize GetMergeCells error: %w", err)
+	}
+	for _, mc := range mergeCells {
+		startCol, startRow, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			return nil, fmt.Errorf("excelize CellNameToCoordinates error: %w", err)
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			return nil, fmt.Errorf("excelize CellNameToCoordinates error: %w", err)
+		}
+
+		for row := startRow - 1; row < endRow && row < len(grid); row++ {
+			for col := startCol - 1; col < endCol && col < width; col++ {
+				grid[row][col] = mc.GetCellValue()
+			}
+		}
+	}
+
+	return grid, nil
+}
+
+// matchDynamicHeader matches header cells of column against every level of
+// dynamic rule, it returns entry with dynamicpos fields parsed from header.
+func (em *ExcelizeMapper) matchDynamicHeader(rules *DynamicRules, grid [][]string, col int) (reflect.Value, bool, error) {
+	// type of interface entries is unknown, their columns are skipped
+	if rules.entryType == nil {
+		return reflect.Value{}, false, nil
+	}
+	templates := append(append([]headerTemplate{}, rules.groups...), rules.template)
+
+	var matches []templateMatch
+	for level, tpl := range templates {
+		header := grid[level][col]
+		if level == len(templates)-1 && header == "" {
+			return reflect.Value{}, false, nil
+		}
+		levelMatches, ok := tpl.match(header)
+		if !ok {
+			return reflect.Value{}, false, nil
+		}
+		if tpl.ambiguous() {
+			cell, _ := excelize.CoordinatesToCellName(col+1, level+1)
+			return reflect.Value{}, false, fmt.Errorf("header %s: dynamic field %s: adjacent placeholders of rule can't be read back", cell, rules.ParentFieldName)
+		}
+		matches = append(matches, levelMatches...)
+	}
+
+	entry := reflect.New(rules.entryType).Elem()
+	for _, m := range matches {
+		field := entry.FieldByName(m.segment.key)
+		column := Column{FormatterKey: m.segment.formatter}
+		if err := em.setFieldValue(field, column, m.text); err != nil {
+			cell, _ := excelize.CoordinatesToCellName(col+1, len(templates))
+			return reflect.Value{}, false, fmt.Errorf("header %s: dynamic field %s: %w", cell, rules.ParentFieldName, err)
+		}
+	}
+
+	return entry, true, nil
+}
+
+// setFieldValue sets field from cell text, columns with formatter are parsed
+// by parser of the same name and skipped if there is none.
+func (em *ExcelizeMapper) setFieldValue(field reflect.Value, column Column, text string) error {
+	if !field.IsValid() || !field.CanSet() {
+		return nil
+	}
+
+	// empty cell of blank zero column is zero value, nil for pointers
+	if text == "" && em.blankZero(column) {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if column.FormatterKey != "" {
+		parse, ok, err := em.lookupParser(column.FormatterKey)
+		if !ok || err != nil {
+			return err
+		}
+		val, err := parse(text)
+		if err != nil {
+			return err
+		}
+		return assignValue(field, val)
+	}
+
+	if parse, ok := em.typeParser(field.Type()); ok {
+		if text == "" && field.Kind() == reflect.Ptr {
+			return nil
+		}
+		val, err := parse(text)
+		if err != nil {
+			return err
+		}
+		return assignValue(field, val)
+	}
+
+	ok, err := setNullable(field, func(inner reflect.Value) error {
+		return em.setFieldValue(inner, Column{}, text)
+	}, text == "")
+	if ok {
+		return err
+	}
+
+	if ok, err := scanCell(field, text); ok {
+		return err
+	}
+
+	if ok, err := unmarshalCell(field, text); ok {
+		return err
+	}
+
+	val, err := convertString(text, field.Type())
+	if err != nil {
+		return err
+	}
+	field.Set(val)
+	return nil
+}
+
+// typeParser returns parser registered for type t or type pointed by t
+func (em *ExcelizeMapper) typeParser(t reflect.Type) (Parse, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	parse, ok := em.options.typeParsers[t]
+	return parse, ok
+}
+
+// assignValue sets field from value returned by parser
+func assignValue(field reflect.Value, val interface{}) error {
+	if val == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	t := field.Type()
+	if rv.Type().AssignableTo(t) {
+		field.Set(rv)
+		return nil
+	}
+
+	ok, err := setNullable(field, func(inner reflect.Value) error {
+		return assignValue(inner, val)
+	}, false)
+	if ok {
+		return err
+	}
+
+	switch {
+	case rv.Type().ConvertibleTo(t):
+		field.Set(rv.Convert(t))
+	case t.Kind() == reflect.Ptr && rv.Type().ConvertibleTo(t.Elem()):
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(rv.Convert(t.Elem()))
+		field.Set(ptr)
+	case rv.Kind() == reflect.String:
+		converted, err := convertString(rv.String(), t)
+		if err != nil {
+			return err
+		}
+		field.Set(converted)
+	default:
+		return fmt.Errorf("cannot assign %T to %s", val, t)
+	}
+	return nil
+}
+
+// convertString converts cell text to value of type t, empty text is zero value
+func convertString(text string, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		if text == "" {
+			return reflect.Zero(t), nil
+		}
+		elem, err := convertString(text, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.String {
+		v.SetString(text)
+		return v, nil
+	}
+	if t.Kind() == reflect.Interface && reflect.TypeOf(text).AssignableTo(t) {
+		v.Set(reflect.ValueOf(text))
+		return v, nil
+	}
+	if text == "" {
+		return v, nil
+	}
+
+	if t == timeType {
+		tm, err := parseTime(text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return v, nil
+	}
+
+	switch {
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid bool %q", text)
+		}
+		v.SetBool(b)
+	case isIntKind(t.Kind()):
+		i, err := strconv.ParseInt(text, 10, t.Bits())
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != float64(int64(f)) {
+				return reflect.Value{}, fmt.Errorf("invalid integer %q", text)
+			}
+			i = int64(f)
+		}
+		if v.OverflowInt(i) {
+			return reflect.Value{}, fmt.Errorf("integer %q overflows %s", text, t)
+		}
+		v.SetInt(i)
+	case isUintKind(t.Kind()):
+		u, err := strconv.ParseUint(text, 10, t.Bits())
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f < 0 || f != float64(uint64(f)) {
+				return reflect.Value{}, fmt.Errorf("invalid unsigned integer %q", text)
+			}
+			u = uint64(f)
+		}
+		if v.OverflowUint(u) {
+			return reflect.Value{}, fmt.Errorf("unsigned integer %q overflows %s", text, t)
+		}
+		v.SetUint(u)
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(text, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid number %q", text)
+		}
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type %s", t)
+	}
+
+	return v, nil
+}
+
+// parseTime parses excel serial date or RFC 3339 text
+func parseTime(text string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(text, 64); err == nil {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+	tm, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", text)
+	}
+	return tm, nil
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUintKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// fieldByIndexForSet returns settable field by index path allocating nil pointers on the way
+func fieldByIndexForSet(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if cell != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func cellError(col, rowIndex int, err error) error {
+	cell, cerr := excelize.CoordinatesToCellName(col+1, rowIndex+1)
+	if cerr != nil {
+		return err
+	}
+	return fmt.Errorf("cell %s: %w", cell, err)
+}
@@ -1,5 +1,13 @@
 package excelizemapper
 
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
 type Column struct {
 	HeaderName   string
 	ColumnWidth  float64
@@ -7,4 +15,260 @@ type Column struct {
 	DefaultValue string
 	FormatterKey string
 	FieldName    string
+	NumFmt       string
+	StyleKey     string
+	// OmitEmpty writes zero value as empty cell
+	OmitEmpty bool
+	// Tags holds all parsed tag attributes including unknown ones.
+	Tags map[string]string
+	// Views holds views column is visible in, empty for all.
+	Views []string
+
+	// fieldIndex is precomputed index path of FieldName
+	fieldIndex []int
+	// valueType is field type without pointer and nullable wrapper, marshal
+	// is its marshaler worked out when schema is parsed
+	valueType reflect.Type
+	marshal   cellMarshaler
+
+	// getter and setter are set for columns of code-first Schema
+	getter    func(row reflect.Value) reflect.Value
+	setter    func(row reflect.Value, val reflect.Value)
+	setType   reflect.Type
+	setterRow reflect.Type
+}
+
+// fieldValue returns value of column field in row
+func (c *Column) fieldValue(row reflect.Value) reflect.Value {
+	if c.getter != nil {
+		return c.getter(row)
+	}
+	return fieldByIndex(row, c.fieldIndex)
+}
+
+// setValueType sets type of field values and works out its marshaler
+func (c *Column) setValueType(t reflect.Type) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if value, _, ok := nullableFields(t); ok {
+		t = t.Field(value).Type
+	}
+	c.valueType = t
+	c.marshal = marshalerOf(t)
+}
+
+// marshalerOf returns marshaler of value type t, schema marshaler is used
+// unless value has another type, e.g. default or dynamic interface value.
+func (c *Column) marshalerOf(t reflect.Type) cellMarshaler {
+	if t == c.valueType {
+		return c.marshal
+	}
+	return marshalerOf(t)
+}
+
+// setColumnValue sets column field of addressable row from cell text
+func (em *ExcelizeMapper) setColumnValue(c *Column, row reflect.Value, text string) error {
+	if c.getter == nil {
+		return em.setFieldValue(c.fieldForSet(row), *c, text)
+	}
+
+	// code-first columns are read only with setter
+	if c.setter == nil {
+		return nil
+	}
+	val := reflect.New(c.setType).Elem()
+	if err := em.setFieldValue(val, *c, text); err != nil {
+		return err
+	}
+	c.setter(row, val)
+	return nil
+}
+
+// fieldForSet returns settable column field in row
+func (c *Column) fieldForSet(row reflect.Value) reflect.Value {
+	return fieldByIndexForSet(row, c.fieldIndex)
+}
+
+// DynamicKey holds the dynamicpos values of one dynamic column keyed by field name.
+type DynamicKey map[string]interface{}
+
+// DynamicHeader is a generated dynamic column header and the key it was rendered from.
+type DynamicHeader struct {
+	Header string
+	// Groups holds labels of parent header rows, outermost first.
+	Groups []string
+	Key    DynamicKey
+}
+
+// headerLevelSep joins header levels into column id
+const headerLevelSep = "\x1f"
+
+// id identifies generated column, headers of different groups may repeat
+func (dh DynamicHeader) id() string {
+	if len(dh.Groups) == 0 {
+		return dh.Header
+	}
+	return strings.Join(dh.Groups, headerLevelSep) + headerLevelSep + dh.Header
+}
+
+// schemaOf returns schema registered by WithSchema or parsed from tags of itemType
+func (em *ExcelizeMapper) schemaOf(itemType reflect.Type) ([]Column, []*DynamicRules, error) {
+	if schema, ok := em.options.schemas[itemType]; ok {
+		columns, err := schema.schemaColumns()
+		if err == nil {
+			err = validateColumns(columns)
+		}
+		return columns, nil, err
+	}
+
+	columns, rules, err := em.parser.parseType(itemType)
+	if err == nil && em.parser.strict {
+		err = em.checkFormatters(columns, rules)
+	}
+	return columns, rules, err
+}
+
+// validateColumns reports negative indexes, duplicate indexes and duplicate
+// headers. Columns of disjoint views may share index and header.
+func validateColumns(columns []Column) error {
+	for i, a := range columns {
+		if a.ColumnIndex < 0 {
+			return fmt.Errorf("field %s: negative index %d", a.FieldName, a.ColumnIndex)
+		}
+		for _, b := range columns[i+1:] {
+			if !sharesView(a.Views, b.Views) {
+				continue
+			}
+			if a.ColumnIndex == b.ColumnIndex {
+				return fmt.Errorf("fields %s and %s: duplicate index %d", a.FieldName, b.FieldName, a.ColumnIndex)
+			}
+			if a.HeaderName == b.HeaderName {
+				return fmt.Errorf("fields %s and %s: duplicate header %q", a.FieldName, b.FieldName, a.HeaderName)
+			}
+		}
+	}
+	return nil
+}
+
+// sharesView reports whether columns of views a and b can be selected together
+func sharesView(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(a, func(view string) bool {
+		return slices.Contains(b, view)
+	})
+}
+
+// checkFormatters reports formatters of schema that are not registered
+func (em *ExcelizeMapper) checkFormatters(columns []Column, dynamicRules []*DynamicRules) error {
+	check := func(fieldName, key, name string) error {
+		if _, ok := em.options.contextMap[name]; ok || name == "" {
+			return nil
+		}
+		_, ok, err := em.lookupFormatter(name)
+		if err != nil {
+			return fmt.Errorf("field %s: key %q: %w", fieldName, key, err)
+		}
+		if !ok {
+			return fmt.Errorf("field %s: key %q: formatter %q not registered", fieldName, key, name)
+		}
+		return nil
+	}
+
+	for _, column := range columns {
+		if err := check(column.FieldName, em.parser.tagFormatKey, column.FormatterKey); err != nil {
+			return err
+		}
+	}
+	for _, rules := range dynamicRules {
+		if err := check(rules.ParentFieldName, em.parser.tagFormatKey, rules.Value.FormatterKey); err != nil {
+			return err
+		}
+		for _, tpl := range append(slices.Clone(rules.groups), rules.template) {
+			for _, seg := range tpl.segments {
+				if err := check(rules.ParentFieldName, em.parser.tagDynamicKey, seg.formatter); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parse returns schema of rows of data
+func (em *ExcelizeMapper) parse(data interface{}) ([]Column, []*DynamicRules, error) {
+	di := indirectValue(reflect.ValueOf(data))
+	if dk := di.Kind(); dk != reflect.Array && dk != reflect.Slice {
+		return nil, nil, fmt.Errorf("data not array or slice")
+	}
+
+	itemType, err := rowTypeOf(di)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return em.schemaOf(itemType)
+}
+
+// Schema returns columns and dynamic rules parsed from sample, sample is a
+// struct, pointer to struct or slice of them.
+//
+// Returned values are copies and safe to modify.
+func (em *ExcelizeMapper) Schema(sample interface{}) ([]Column, []DynamicRules, error) {
+	sv := indirectValue(reflect.ValueOf(sample))
+	if !sv.IsValid() {
+		return nil, nil, fmt.Errorf("sample is nil")
+	}
+
+	itemType := sv.Type()
+	if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
+		var err error
+		if itemType, err = rowTypeOf(sv); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	columns, rules, err := em.schemaOf(itemType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outColumns, outRules := copySchema(columns, rules)
+	return outColumns, outRules, nil
+}
+
+// Schema returns columns and dynamic rules of T, see ExcelizeMapper.Schema.
+func (m *Mapper[T]) Schema() ([]Column, []DynamicRules, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	columns, rules := copySchema(m.columns, m.rules)
+	return columns, rules, nil
+}
+
+func copySchema(columns []Column, rules []*DynamicRules) ([]Column, []DynamicRules) {
+	outColumns := make([]Column, len(columns))
+	for i, column := range columns {
+		outColumns[i] = column.clone()
+	}
+
+	outRules := make([]DynamicRules, len(rules))
+	for i, rule := range rules {
+		outRules[i] = *rule
+		outRules[i].Mappings = maps.Clone(rule.Mappings)
+		outRules[i].PosFields = slices.Clone(rule.PosFields)
+		outRules[i].Value = rule.Value.clone()
+		outRules[i].Views = slices.Clone(rule.Views)
+	}
+
+	return outColumns, outRules
+}
+
+func (c Column) clone() Column {
+	c.Tags = maps.Clone(c.Tags)
+	c.Views = slices.Clone(c.Views)
+	return c
 }
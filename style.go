@@ -0,0 +1,141 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type cellStyleKey struct {
+	numFmt string
+	style  string
+}
+
+func (em *ExcelizeMapper) setColumnWidth(f *excelize.File, sheet string, colIndex int, column Column) error {
+	width := em.options.defaultWidth
+	if column.ColumnWidth > 0 {
+		width = column.ColumnWidth
+	}
+	if width <= 0 {
+		return nil
+	}
+
+	colName, err := excelize.ColumnNumberToName(colIndex + 1)
+	if err != nil {
+		return fmt.Errorf("excelize ColumnNumberToName error: %w", err)
+	}
+	return f.SetColWidth(sheet, colName, colName, width)
+}
+
+// setColumnStyle applies number format and style of column to its data cells,
+// styles caches style id by column settings.
+func (em *ExcelizeMapper) setColumnStyle(f *excelize.File, sheet string, colIndex, headerRows, rowCount int, column Column, styles map[cellStyleKey]int) error {
+	// built-in number formatter sets number format unless column has one
+	if column.NumFmt == "" {
+		number, err := em.numberFormatter(column.FormatterKey)
+		if err != nil {
+			return err
+		}
+		if number != nil {
+			column.NumFmt = number.numFmt
+		}
+	}
+	if rowCount == 0 || (column.NumFmt == "" && column.StyleKey == "") {
+		return nil
+	}
+
+	styleID, err := em.styleID(f, column.NumFmt, column.StyleKey, styles)
+	if err != nil {
+		return err
+	}
+
+	topCell, err := excelize.CoordinatesToCellName(colIndex+1, headerRows+1)
+	if err != nil {
+		return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+	}
+	bottomCell, err := excelize.CoordinatesToCellName(colIndex+1, headerRows+rowCount)
+	if err != nil {
+		return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+	}
+
+	if err := f.SetCellStyle(sheet, topCell, bottomCell, styleID); err != nil {
+		return fmt.Errorf("excelize SetCellStyle error: %w", err)
+	}
+	return nil
+}
+
+// styleID returns id of style with number format and registered style,
+// styles caches style id by settings.
+func (em *ExcelizeMapper) styleID(f *excelize.File, numFmt, styleKey string, styles map[cellStyleKey]int) (int, error) {
+	key := cellStyleKey{numFmt: numFmt, style: styleKey}
+	if styleID, ok := styles[key]; ok {
+		return styleID, nil
+	}
+
+	style := &excelize.Style{}
+	if styleKey != "" {
+		registered, ok := em.options.styles[styleKey]
+		if !ok {
+			return 0, fmt.Errorf("style %q not found", styleKey)
+		}
+		copied := *registered
+		style = &copied
+	}
+	if numFmt != "" {
+		style.CustomNumFmt = &numFmt
+	}
+
+	styleID, err := f.NewStyle(style)
+	if err != nil {
+		return 0, fmt.Errorf("excelize NewStyle error: %w", err)
+	}
+	styles[key] = styleID
+	return styleID, nil
+}
+
+// fractionCell is data cell written by number formatter with fraction, zero based
+type fractionCell struct {
+	col, row int
+	numFmt   string
+	styleKey string
+}
+
+// fractionNumFmt returns number format of column cells with fraction, empty
+// if column number format does not depend on value.
+func (em *ExcelizeMapper) fractionNumFmt(column Column) (string, error) {
+	if column.NumFmt != "" {
+		return "", nil
+	}
+	number, err := em.numberFormatter(column.FormatterKey)
+	if number == nil || err != nil {
+		return "", err
+	}
+	return number.fractionNumFmt, nil
+}
+
+// appendFraction records cell when val is number with fraction
+func appendFraction(cells []fractionCell, numFmt string, column Column, col, row int, val interface{}) []fractionCell {
+	if f, ok := val.(float64); ok && numFmt != "" && f != math.Trunc(f) {
+		cells = append(cells, fractionCell{col: col, row: row, numFmt: numFmt, styleKey: column.StyleKey})
+	}
+	return cells
+}
+
+// setFractionStyles overrides column style of cells with fraction
+func (em *ExcelizeMapper) setFractionStyles(f *excelize.File, sheet string, cells []fractionCell, styles map[cellStyleKey]int) error {
+	for _, c := range cells {
+		styleID, err := em.styleID(f, c.numFmt, c.styleKey, styles)
+		if err != nil {
+			return err
+		}
+		cell, err := excelize.CoordinatesToCellName(c.col+1, c.row+1)
+		if err != nil {
+			return fmt.Errorf("excelize CoordinatesToCellName error: %w", err)
+		}
+		if err := f.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+			return fmt.Errorf("excelize SetCellStyle error: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+parseTags parses tag value into key-value pairs.
+
+Grammar:
+
+	tag   = pair { ";" pair }
+	pair  = key [ ":" value ]
+	value = text | "'" quoted "'"
+
+Key and unquoted value are trimmed, the first ":" separates key and value,
+so the value may hold ":" unescaped. In unquoted value "\;" is literal ";"
+and "\\" is literal "\". Quoted value keeps ";" and spaces, "\'" is literal
+"'" and "\\" is literal "\". Key without value is a flag, e.g. "omitempty".
+
+	excelize-mapper:"header:'Ratio: A;B';width:20"
+
+Struct tag values are Go quoted strings, so backslash is doubled in source:
+
+	excelize-mapper:"header:Ratio A\\;B"
+
+Malformed pairs are returned as error, pairs parsed before and after it are
+still returned.
+*/
+func (p *parser) parseTags(tag string) (map[string]string, error) {
+	kv := make(map[string]string)
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	var key, val strings.Builder
+	hasValue, quoted, inQuote, afterQuote := false, false, false, false
+
+	flush := func() {
+		k := strings.TrimSpace(key.String())
+		v := val.String()
+		if !quoted {
+			v = strings.TrimSpace(v)
+		}
+		switch {
+		case k == "" && (hasValue || v != ""):
+			fail(fmt.Errorf("missing key before %q", v))
+		case k == "":
+		default:
+			if _, ok := kv[k]; ok {
+				fail(fmt.Errorf("duplicate key %q", k))
+			}
+			kv[k] = v
+		}
+		key.Reset()
+		val.Reset()
+		hasValue, quoted, afterQuote = false, false, false
+	}
+
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case inQuote:
+			if c == '\\' && i+1 < len(tag) && (tag[i+1] == '\'' || tag[i+1] == '\\') {
+				val.WriteByte(tag[i+1])
+				i++
+			} else if c == '\'' {
+				inQuote, afterQuote = false, true
+			} else {
+				val.WriteByte(c)
+			}
+		case strings.HasPrefix(tag[i:], p.tagDelim):
+			flush()
+			i += len(p.tagDelim) - 1
+		case afterQuote:
+			if c != ' ' {
+				fail(fmt.Errorf("unexpected %q after quoted value of key %q", c, strings.TrimSpace(key.String())))
+			}
+		case c == '\\' && i+1 < len(tag) && tag[i+1] == '\\':
+			val.WriteByte('\\')
+			i++
+		case c == '\\' && strings.HasPrefix(tag[i+1:], p.tagDelim):
+			val.WriteString(p.tagDelim)
+			i += len(p.tagDelim)
+		case !hasValue && c == ':':
+			hasValue = true
+		case !hasValue:
+			key.WriteByte(c)
+		case c == '\'' && strings.TrimSpace(val.String()) == "":
+			val.Reset()
+			quoted, inQuote = true, true
+		default:
+			val.WriteByte(c)
+		}
+	}
+	if inQuote {
+		fail(fmt.Errorf("unterminated quoted value of key %q", strings.TrimSpace(key.String())))
+	}
+	flush()
+
+	return kv, firstErr
+}
+
+// getTagsByKey returns parsed tags of field, nil if field has no tag.
+// In strict mode malformed and unknown keys are errors.
+func (p *parser) getTagsByKey(field reflect.StructField) (map[string]string, error) {
+	fullTagVal, ok := field.Tag.Lookup(p.tagKey)
+	if !ok && p.strict && strings.Contains(string(field.Tag), p.tagKey+":") {
+		return nil, fmt.Errorf("field %s: bad syntax of struct tag %q", field.Name, field.Tag)
+	}
+	if fullTagVal == "" {
+		return nil, nil
+	}
+
+	tags, err := p.parseTags(fullTagVal)
+	if !p.strict {
+		return tags, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", field.Name, err)
+	}
+	for key := range tags {
+		if !p.knownKey(key) {
+			return nil, fmt.Errorf("field %s: unknown key %q", field.Name, key)
+		}
+	}
+	return tags, nil
+}
+
+func (p *parser) knownKey(key string) bool {
+	switch key {
+	case p.tagHeaderKey, p.tagIndexKey, p.tagDefaultKey, p.tagFormatKey, p.tagWidthKey,
+		p.tagDynamicKey, p.tagDynamicPosKey, p.tagDynamicValKey, p.tagSortKey, p.tagAggregateKey,
+		p.tagNumFmtKey, p.tagStyleKey, p.tagAfterKey, p.tagViewsKey, p.tagOmitEmptyKey:
+		return true
+	}
+	return false
+}
+
+// parseWidth parses "width" value, it must be non negative number
+func parseWidth(text string) (float64, error) {
+	width, err := strconv.ParseFloat(text, 64)
+	if err != nil || width < 0 {
+		return 0, fmt.Errorf("invalid width %q", text)
+	}
+	return width, nil
+}
+
+// parseFlag parses flag value, empty value of bare key is true
+func parseFlag(text string) (bool, error) {
+	if text == "" {
+		return true, nil
+	}
+	flag, err := strconv.ParseBool(text)
+	if err != nil {
+		return false, fmt.Errorf("invalid bool %q", text)
+	}
+	return flag, nil
+}
+
+// parseIndex parses "index" value, it must be non negative int
+func parseIndex(text string) (int, error) {
+	index, err := strconv.Atoi(text)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid index %q", text)
+	}
+	return index, nil
+}
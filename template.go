@@ -0,0 +1,291 @@
+package excelizemapper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+headerTemplate is a compiled dynamic header rule.
+
+Supported placeholders:
+
+	{Name}             value of the dynamicpos field named Name
+	{Name:%04d}        value printed with the fmt verb
+	{Name|quarter}     value passed through the "quarter" formatter
+	{Name:%3s|quarter} formatter output printed with the fmt verb
+	$1                 legacy placeholder, equal to {$1}
+	YEAR               legacy placeholder in rule without braces,
+	                   any declared dynamicpos key is replaced
+
+Name is looked up by dynamicpos tag value first, then by field name.
+Use "{{" and "}}" for literal braces. Rule with adjacent placeholders,
+e.g. "$1$2", is written but can't be read back.
+
+A dynamic rule may declare header hierarchy separated by ">", e.g.
+"{Year}>Q{Quarter}" renders "2021" as merged group cell above "Q1", "Q2".
+Use ">>" for literal ">".
+*/
+type headerTemplate struct {
+	segments []templateSegment
+	pattern  *regexp.Regexp
+}
+
+// formatterLookup returns formatter by name
+type formatterLookup func(name string) (Format, bool, error)
+
+type templateSegment struct {
+	literal   string
+	key       string
+	verb      string
+	formatter string
+}
+
+func (s templateSegment) isPlaceholder() bool {
+	return s.key != ""
+}
+
+// splitHeaderLevels splits dynamic rule into group rules and leaf rule
+func splitHeaderLevels(rule string) []string {
+	var levels []string
+	var level strings.Builder
+	depth := 0
+	for i := 0; i < len(rule); i++ {
+		c := rule[i]
+		switch {
+		case c == '>' && i+1 < len(rule) && rule[i+1] == '>':
+			level.WriteByte('>')
+			i++
+			continue
+		case c == '>' && depth == 0:
+			levels = append(levels, level.String())
+			level.Reset()
+			continue
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		}
+		level.WriteByte(c)
+	}
+	return append(levels, level.String())
+}
+
+// compileHeaderTemplate compiles one level of dynamic rule. Legacy rule without
+// "{" placeholders has declared dynamicpos keys replaced in place, longest key first,
+// e.g. "YEAR-QTR" or "$1Q$2".
+func compileHeaderTemplate(rule string, keys []string) (headerTemplate, error) {
+	var tpl headerTemplate
+	var literal strings.Builder
+
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			tpl.segments = append(tpl.segments, templateSegment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(rule); i++ {
+		c := rule[i]
+		switch {
+		case c == '{' && i+1 < len(rule) && rule[i+1] == '{':
+			literal.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(rule) && rule[i+1] == '}':
+			literal.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(rule[i:], '}')
+			if end < 0 {
+				return headerTemplate{}, fmt.Errorf("unclosed placeholder at %d in %q", i, rule)
+			}
+			seg, err := parsePlaceholder(rule[i+1 : i+end])
+			if err != nil {
+				return headerTemplate{}, fmt.Errorf("%w in %q", err, rule)
+			}
+			flushLiteral()
+			tpl.segments = append(tpl.segments, seg)
+			i += end
+		case c == '}':
+			return headerTemplate{}, fmt.Errorf("unexpected '}' at %d in %q", i, rule)
+		case legacyKeyAt(rule[i:], keys) != "":
+			key := legacyKeyAt(rule[i:], keys)
+			flushLiteral()
+			tpl.segments = append(tpl.segments, templateSegment{key: key, verb: "%v"})
+			i += len(key) - 1
+		case c == '$' && i+1 < len(rule) && isDigit(rule[i+1]):
+			end := i + 1
+			for end < len(rule) && isDigit(rule[end]) {
+				end++
+			}
+			flushLiteral()
+			tpl.segments = append(tpl.segments, templateSegment{key: rule[i:end], verb: "%v"})
+			i = end - 1
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flushLiteral()
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, seg := range tpl.segments {
+		if seg.isPlaceholder() {
+			pattern.WriteString("(.*?)")
+		} else {
+			pattern.WriteString(regexp.QuoteMeta(seg.literal))
+		}
+	}
+	pattern.WriteString("$")
+	tpl.pattern = regexp.MustCompile(pattern.String())
+
+	return tpl, nil
+}
+
+func parsePlaceholder(body string) (templateSegment, error) {
+	seg := templateSegment{verb: "%v"}
+
+	if name, formatter, ok := strings.Cut(body, "|"); ok {
+		body = name
+		seg.formatter = strings.TrimSpace(formatter)
+		if seg.formatter == "" {
+			return seg, fmt.Errorf("empty formatter in placeholder {%s}", body)
+		}
+	}
+
+	if name, verb, ok := strings.Cut(body, ":"); ok {
+		body = name
+		verb = strings.TrimSpace(verb)
+		if !strings.HasPrefix(verb, "%") {
+			verb = "%" + verb
+		}
+		seg.verb = verb
+	}
+
+	seg.key = strings.TrimSpace(body)
+	if seg.key == "" {
+		return seg, fmt.Errorf("empty placeholder name")
+	}
+
+	return seg, nil
+}
+
+// legacyKeys returns dynamicpos keys replaced in legacy rule, longest first
+func legacyKeys(rule string, mappings map[string]string) []string {
+	if strings.Contains(rule, "{") {
+		return nil
+	}
+	keys := make([]string, 0, len(mappings))
+	for key := range mappings {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// legacyKeyAt returns the first of keys text starts with
+func legacyKeyAt(text string, keys []string) string {
+	for _, key := range keys {
+		if strings.HasPrefix(text, key) {
+			return key
+		}
+	}
+	return ""
+}
+
+// resolve maps every placeholder to a field name of the dynamic entry.
+func (t *headerTemplate) resolve(mappings map[string]string) error {
+	fields := make(map[string]bool, len(mappings))
+	for _, field := range mappings {
+		fields[field] = true
+	}
+
+	for i, seg := range t.segments {
+		if !seg.isPlaceholder() {
+			continue
+		}
+		if field, ok := mappings[seg.key]; ok {
+			t.segments[i].key = field
+			continue
+		}
+		if fields[seg.key] {
+			continue
+		}
+		return fmt.Errorf("unknown placeholder %q", seg.key)
+	}
+
+	return nil
+}
+
+func (t *headerTemplate) render(key DynamicKey, formatters formatterLookup) (string, error) {
+	var sb strings.Builder
+	for _, seg := range t.segments {
+		if !seg.isPlaceholder() {
+			sb.WriteString(seg.literal)
+			continue
+		}
+
+		val := key[seg.key]
+		if val == nil {
+			val = ""
+		}
+
+		if seg.formatter != "" {
+			format, ok, err := formatters(seg.formatter)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", fmt.Errorf("formatter %q not found", seg.formatter)
+			}
+			val = format(val)
+		}
+
+		fmt.Fprintf(&sb, seg.verb, val)
+	}
+	return sb.String(), nil
+}
+
+// ambiguous reports whether template has adjacent placeholders, their text
+// can't be told apart when header is parsed back.
+func (t *headerTemplate) ambiguous() bool {
+	for i := 1; i < len(t.segments); i++ {
+		if t.segments[i-1].isPlaceholder() && t.segments[i].isPlaceholder() {
+			return true
+		}
+	}
+	return false
+}
+
+// match parses header back, it returns text of every placeholder.
+func (t *headerTemplate) match(header string) ([]templateMatch, bool) {
+	sub := t.pattern.FindStringSubmatch(header)
+	if sub == nil {
+		return nil, false
+	}
+
+	var matches []templateMatch
+	group := 1
+	for _, seg := range t.segments {
+		if !seg.isPlaceholder() {
+			continue
+		}
+		matches = append(matches, templateMatch{segment: seg, text: strings.TrimSpace(sub[group])})
+		group++
+	}
+	return matches, true
+}
+
+type templateMatch struct {
+	segment templateSegment
+	text    string
+}
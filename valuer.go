@@ -0,0 +1,124 @@
+package excelizemapper
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// CellValuer is implemented by types that control their cell value,
+// it is used for columns without formatter.
+type CellValuer interface {
+	CellValue() (interface{}, error)
+}
+
+// CellUnmarshaler is implemented by types that parse their cell text,
+// it is used by GetData for columns without formatter.
+type CellUnmarshaler interface {
+	UnmarshalCell(text string) error
+}
+
+var (
+	cellValuerType      = reflect.TypeOf((*CellValuer)(nil)).Elem()
+	cellUnmarshalerType = reflect.TypeOf((*CellUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// implements reports whether value or pointer to it implements iface,
+// time.Time is written and read natively.
+func implements(t, iface reflect.Type) bool {
+	if t == timeType || t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// asInterface returns value as iface, value is copied if only its pointer implements iface
+func asInterface(v reflect.Value, iface reflect.Type) interface{} {
+	if v.Type().Implements(iface) {
+		return v.Interface()
+	}
+	if !v.CanAddr() {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr.Elem()
+	}
+	return v.Addr().Interface()
+}
+
+// cellMarshaler converts value by marshal interface of its type
+type cellMarshaler func(v reflect.Value) (interface{}, error)
+
+// marshalers caches cellMarshaler by type, nil for types written natively
+var marshalers sync.Map
+
+// marshalerOf returns cached marshaler of type t, nil if t has no marshal interface
+func marshalerOf(t reflect.Type) cellMarshaler {
+	if cached, ok := marshalers.Load(t); ok {
+		return cached.(cellMarshaler)
+	}
+	marshal := newMarshaler(t)
+	marshalers.Store(t, marshal)
+	return marshal
+}
+
+// newMarshaler picks CellValuer, driver.Valuer, encoding.TextMarshaler or fmt.Stringer
+func newMarshaler(t reflect.Type) cellMarshaler {
+	switch {
+	case implements(t, cellValuerType):
+		return func(v reflect.Value) (interface{}, error) {
+			return asInterface(v, cellValuerType).(CellValuer).CellValue()
+		}
+	case implements(t, valuerType):
+		return func(v reflect.Value) (interface{}, error) {
+			val, err := asInterface(v, valuerType).(driver.Valuer).Value()
+			if val == nil {
+				val = ""
+			}
+			return val, err
+		}
+	case implements(t, textMarshalerType):
+		return func(v reflect.Value) (interface{}, error) {
+			text, err := asInterface(v, textMarshalerType).(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}
+	case implements(t, stringerType):
+		return func(v reflect.Value) (interface{}, error) {
+			return asInterface(v, stringerType).(fmt.Stringer).String(), nil
+		}
+	}
+	return nil
+}
+
+// unmarshalCell sets field by CellUnmarshaler or encoding.TextUnmarshaler,
+// nil pointer field is allocated. Fields written by marshal interfaces
+// without matching unmarshal interface can't be read back and are skipped.
+func unmarshalCell(field reflect.Value, text string) (bool, error) {
+	t := field.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !implements(t, cellUnmarshalerType) && !implements(t, textUnmarshalerType) {
+		return implements(t, cellValuerType) || implements(t, valuerType) ||
+			implements(t, textMarshalerType) || implements(t, stringerType), nil
+	}
+	if text == "" {
+		return true, nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(t))
+		}
+		field = field.Elem()
+	}
+
+	if implements(t, cellUnmarshalerType) {
+		return true, field.Addr().Interface().(CellUnmarshaler).UnmarshalCell(text)
+	}
+	return true, field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+}
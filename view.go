@@ -0,0 +1,92 @@
+package excelizemapper
+
+import (
+	"slices"
+	"strings"
+)
+
+// selectColumns filters and reorders schema by WithView, WithColumns and
+// WithoutColumns options, selected static columns are renumbered in order.
+// Cached schema is never modified.
+func (em *ExcelizeMapper) selectColumns(columns []Column, dynamicRules []*DynamicRules) ([]Column, []*DynamicRules) {
+	o := em.options
+	if o.view == "" && len(o.columns) == 0 && len(o.withoutColumns) == 0 {
+		return columns, dynamicRules
+	}
+
+	selected := func(fieldName string, views []string) bool {
+		if o.view != "" && len(views) > 0 && !slices.Contains(views, o.view) {
+			return false
+		}
+		if slices.ContainsFunc(o.withoutColumns, func(name string) bool { return matchFieldName(fieldName, name) }) {
+			return false
+		}
+		return len(o.columns) == 0 || columnOrder(o.columns, fieldName) >= 0
+	}
+
+	outColumns := make([]Column, 0, len(columns))
+	for _, column := range columns {
+		if selected(column.FieldName, column.Views) {
+			outColumns = append(outColumns, column)
+		}
+	}
+	if len(o.columns) > 0 {
+		slices.SortStableFunc(outColumns, func(a, b Column) int {
+			return columnOrder(o.columns, a.FieldName) - columnOrder(o.columns, b.FieldName)
+		})
+	}
+
+	// dynamic block keeps its place before static column it preceded, block
+	// whose column is not selected is placed last like unselected "after"
+	newIndex := func(index int) int {
+		anchor := ""
+		anchorIndex := -1
+		for _, column := range columns {
+			if column.ColumnIndex >= index && (anchorIndex < 0 || column.ColumnIndex < anchorIndex) {
+				anchor, anchorIndex = column.FieldName, column.ColumnIndex
+			}
+		}
+		if i := slices.IndexFunc(outColumns, func(c Column) bool { return c.FieldName == anchor }); i >= 0 {
+			return i
+		}
+		return -1
+	}
+
+	outRules := make([]*DynamicRules, 0, len(dynamicRules))
+	for _, rules := range dynamicRules {
+		if !selected(rules.ParentFieldName, rules.Views) {
+			continue
+		}
+
+		copied := *rules
+		if copied.Index >= 0 {
+			copied.Index = newIndex(copied.Index)
+		}
+		if copied.After != "" && !slices.ContainsFunc(outColumns, func(c Column) bool {
+			return matchFieldName(c.FieldName, copied.After)
+		}) {
+			// anchor is not selected, place block last
+			copied.After = ""
+			copied.Index = -1
+		}
+		outRules = append(outRules, &copied)
+	}
+
+	for i := range outColumns {
+		outColumns[i].ColumnIndex = i
+	}
+
+	return outColumns, outRules
+}
+
+// columnOrder returns position of field in names, -1 if it is not listed
+func columnOrder(names []string, fieldName string) int {
+	return slices.IndexFunc(names, func(name string) bool {
+		return matchFieldName(fieldName, name)
+	})
+}
+
+// matchFieldName reports whether name is full field path or its last part
+func matchFieldName(fieldName, name string) bool {
+	return fieldName == name || strings.HasSuffix(fieldName, "."+name)
+}